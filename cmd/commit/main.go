@@ -0,0 +1,55 @@
+// The commit binary writes the commitment file of each raffle folder passed as
+// an argument, recording the SHA-256 of its manifest and all of the manifest's
+// lists. It MUST be run, and the commitment file pushed alongside the
+// announcement of the entropy block, before the block is mined; it therefore
+// refuses to commit to a raffle whose entropy is already set.
+//
+// Usage:
+//
+//	go run ./cmd/commit <raffle folder>...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: commit <raffle folder>...")
+		os.Exit(1)
+	}
+
+	for _, dir := range flag.Args() {
+		if err := commit(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func commit(dir string) error {
+	e, err := entropy.ParseFile(filepath.Join(dir, entropy.FileName))
+	if err != nil {
+		return err
+	}
+	if !e.Pending() {
+		return fmt.Errorf("entropy already set to hash of block %d; lists MUST be committed to before the block is mined", e.Block)
+	}
+
+	m, err := raffle.Load(filepath.Join(dir, raffle.ManifestFile))
+	if err != nil {
+		return err
+	}
+	if err := m.WriteCommitment(); err != nil {
+		return err
+	}
+	fmt.Printf("%s: committed to manifest and lists for block %d\n", m.CommitmentPath(), e.Block)
+	return nil
+}
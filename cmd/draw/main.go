@@ -0,0 +1,96 @@
+// The draw binary reads lines from stdin and shuffles them in a verifiable
+// manner, reproducing the output of `ethier shuffle` bit for bit. All lines are
+// trimmed of surrounding whitespace, empty lines are dropped, and the remainder
+// sorted before shuffling, so the order of the input does not matter.
+//
+// With --distinct, duplicate lines act as additional entries but each value
+// can only be drawn once. With --weighted, lines MAY be of the form
+// `value,weight`, equivalent to value being repeated weight times.
+//
+// With --trace, a JSON record of every step of the shuffle is written to the
+// specified file, for comparison against independent implementations; see
+// shuffle.Trace for the format.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/proofxyz/raffles/shuffle"
+)
+
+func main() {
+	var (
+		entropyHex, trace  string
+		number             int
+		distinct, weighted bool
+	)
+	flag.StringVar(&entropyHex, "entropy", "", "Hexadecimal source of entropy to control shuffling.")
+	flag.StringVar(&entropyHex, "e", "", "Shorthand for --entropy.")
+	flag.IntVar(&number, "number", 0, "Output first n values; 0 = all.")
+	flag.IntVar(&number, "n", 0, "Shorthand for --number.")
+	flag.BoolVar(&distinct, "distinct", false, "Output the first n distinct values; 0 = all distinct values.")
+	flag.BoolVar(&weighted, "weighted", false, "Accept lines of the form `value,weight`.")
+	flag.StringVar(&trace, "trace", "", "If non-empty, path to which a JSON trace of the shuffle is written.")
+	flag.Parse()
+
+	if err := run(entropyHex, number, distinct, weighted, trace); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(entropyHex string, number int, distinct, weighted bool, tracePath string) error {
+	if entropyHex == "" {
+		return fmt.Errorf("--entropy flag not specified")
+	}
+	if number < 0 {
+		return fmt.Errorf("--number must be non-negative; got %d", number)
+	}
+	entropy, err := shuffle.DecodeHex(entropyHex)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "External entropy: %#x\n", entropy)
+
+	read := shuffle.SortedNonEmpty
+	if weighted {
+		read = shuffle.SortedWeighted
+	}
+	lines, err := read(os.Stdin)
+	if err != nil {
+		return err
+	}
+	if tracePath == "" {
+		if err := shuffle.Shuffle(entropy, lines); err != nil {
+			return fmt.Errorf("shuffle.Shuffle(): %v", err)
+		}
+	} else {
+		tr, err := shuffle.ShuffleTrace(entropy, lines)
+		if err != nil {
+			return fmt.Errorf("shuffle.ShuffleTrace(): %v", err)
+		}
+		buf, err := json.MarshalIndent(tr, "", "  ")
+		if err != nil {
+			return fmt.Errorf("json.MarshalIndent(%T): %v", tr, err)
+		}
+		if err := os.WriteFile(tracePath, append(buf, '\n'), 0644); err != nil {
+			return fmt.Errorf("os.WriteFile(%q): %v", tracePath, err)
+		}
+		fmt.Fprintf(os.Stderr, "Trace written to %s\n", tracePath)
+	}
+
+	k := len(lines)
+	if distinct {
+		lines = shuffle.SelectDistinct(lines, number)
+	} else {
+		lines = shuffle.Select(lines, number)
+	}
+	fmt.Fprintf(os.Stderr, "Selecting %d of %d\n", len(lines), k)
+	fmt.Printf("%s\n", bytes.Join(lines, []byte("\n")))
+
+	return nil
+}
@@ -0,0 +1,205 @@
+// The lint binary checks every raffle folder in the repository, reporting
+// problems that would otherwise silently disable or alter a draw.
+//
+// The lists of raffles that have already been drawn, i.e. that have their
+// entropy set, aren't checked as they can't be changed without altering the
+// results; warnings that can never be acted upon would only teach people to
+// ignore the output. Problems with the lists of other raffles can be corrected
+// with -fix, which rewrites them in a canonical form that draws identically;
+// see raffle.CanonicalList. Lists that have been committed to or have results
+// are never rewritten as their hashes pin the original bytes.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	root := flag.String("root", ".", "Root of the tree in which to search for raffle folders.")
+	fix := flag.Bool("fix", false, "Rewrite lists in canonical form, correcting all problems that don't affect the draw.")
+	flag.Parse()
+
+	n, err := run(*root, *fix)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", n)
+		os.Exit(1)
+	}
+}
+
+// run lints all raffle folders under root, printing problems to stdout and
+// returning the number found.
+func run(root string, fix bool) (int, error) {
+	dirs, err := raffle.Dirs(root)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, dir := range dirs {
+		drawn, problems := lintEntropy(dir)
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", dir, p)
+			n++
+		}
+
+		for _, p := range lintCommitment(dir, drawn) {
+			fmt.Printf("%s: %s\n", dir, p)
+			n++
+		}
+
+		if drawn {
+			continue
+		}
+		problems, err := lintLists(dir, fix)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", dir, p)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// lintEntropy returns all problems with the entropy file in dir, and whether
+// the raffle has been drawn, i.e. its entropy is set.
+func lintEntropy(dir string) (bool, []string) {
+	path := filepath.Join(dir, entropy.FileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, []string{"raffle manifest without an entropy file"}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, []string{err.Error()}
+	}
+	defer f.Close()
+
+	e, err := entropy.Parse(f)
+	if err != nil {
+		return false, []string{fmt.Sprintf("%s: %v", entropy.FileName, err)}
+	}
+	if e.Pending() {
+		fmt.Fprintf(os.Stderr, "%s: pending hash of block %d\n", dir, e.Block)
+		return false, nil
+	}
+	return true, nil
+}
+
+// lintCommitment returns all problems with the commitment to the lists of the
+// manifest in dir, if any. A missing commitment is only a problem if the
+// raffle is yet to be drawn, as historical raffles predate commitments.
+func lintCommitment(dir string, drawn bool) []string {
+	path := filepath.Join(dir, raffle.ManifestFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	m, err := raffle.Load(path)
+	if err != nil {
+		// Reported by lintLists()
+		return nil
+	}
+
+	switch err := m.VerifyCommitment(); {
+	case errors.Is(err, raffle.ErrNoCommitment):
+		if !drawn {
+			return []string{fmt.Sprintf("no %s to lists; run `go run ./cmd/commit %s` before the entropy block is mined", raffle.CommitmentFile, dir)}
+		}
+	case err != nil:
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// lintLists returns all problems with the lists of the manifest in dir, if
+// any. If fix is true, lists with fixable problems are first rewritten in
+// canonical form, and these problems aren't returned, unless the lists have
+// been committed to or have results, the hashes of which would no longer
+// match. The raffle MUST NOT have been drawn.
+func lintLists(dir string, fix bool) ([]string, error) {
+	path := filepath.Join(dir, raffle.ManifestFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	m, err := raffle.Load(path)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+
+	_, err = os.Stat(m.CommitmentPath())
+	committed := err == nil
+	_, err = os.Stat(filepath.Join(dir, raffle.ResultsFile))
+	hasResults := err == nil
+	fixable := !committed && !hasResults
+
+	var problems []string
+	linted := make(map[string]bool)
+	for _, d := range m.AllDraws() {
+		list := m.ListPath(d)
+		if linted[list] {
+			continue
+		}
+		linted[list] = true
+
+		buf, err := os.ReadFile(list)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		ps := raffle.LintList(buf, d)
+		if fix && fixable {
+			if ps, err = fixList(list, buf, d, ps); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range ps {
+			msg := fmt.Sprintf("%s: %v", d.List, p)
+			if p.Fixable && fixable {
+				msg += " (fixable with -fix)"
+			}
+			problems = append(problems, msg)
+		}
+	}
+	return problems, nil
+}
+
+// fixList rewrites the list at path, with contents buf, in canonical form if
+// any of its problems are fixable, returning the problems that remain.
+func fixList(path string, buf []byte, d raffle.Draw, problems []raffle.Problem) ([]raffle.Problem, error) {
+	var remaining []raffle.Problem
+	for _, p := range problems {
+		if !p.Fixable {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) == len(problems) {
+		return problems, nil
+	}
+
+	canon := raffle.CanonicalList(buf)
+	same, err := raffle.SameDraws(buf, canon, d)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %v", path, err)
+	}
+	if !same {
+		return nil, fmt.Errorf("%q: canonical form would alter draws", path)
+	}
+	if err := os.WriteFile(path, canon, 0644); err != nil {
+		return nil, fmt.Errorf("os.WriteFile(%q): %v", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: rewritten in canonical form\n", path)
+	return remaining, nil
+}
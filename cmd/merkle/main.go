@@ -0,0 +1,98 @@
+// The merkle binary builds a keccak256 Merkle tree over each list of the raffle
+// folders passed as arguments, writes the roots to merkle.json in each folder,
+// and optionally exports an inclusion proof of every entry as JSON. See the
+// merkle package for the construction of the tree.
+//
+// Usage:
+//
+//	go run ./cmd/merkle [-proofs <dir>] <raffle folder>...
+//
+// Proofs are written to <dir>/<raffle folder>/<list>/<value>.json, or
+// entry-<index>.json for values that aren't safe file names.
+//
+// The roots are confirmed, alongside the results, by
+// `go run ./cmd/raffle -check_results`.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/proofxyz/raffles/merkle"
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	proofs := flag.String("proofs", "", "If non-empty, directory to which per-entry inclusion proofs are exported.")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: merkle [-proofs <dir>] <raffle folder>...")
+		os.Exit(1)
+	}
+
+	for _, dir := range flag.Args() {
+		if err := run(dir, *proofs); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func run(dir, proofsDir string) error {
+	m, err := raffle.Load(filepath.Join(dir, raffle.ManifestFile))
+	if err != nil {
+		return err
+	}
+
+	roots, trees, err := m.MerkleTrees()
+	if err != nil {
+		return err
+	}
+	for i, t := range trees {
+		list := roots.Lists[i].List
+		fmt.Printf("%s: root %v over %d entries\n", filepath.Join(dir, list), t.Root(), len(t.Entries()))
+
+		if proofsDir != "" {
+			if err := exportProofs(filepath.Join(proofsDir, dir, list), list, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.WriteMerkleRoots(roots)
+}
+
+// safeName matches values that are used, unchanged, as file names.
+var safeName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// exportProofs writes the proof of every entry of the tree to dir.
+func exportProofs(dir, list string, t *merkle.Tree) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll(%q): %v", dir, err)
+	}
+	for i, e := range t.Entries() {
+		name := e.Value
+		if !safeName.MatchString(name) {
+			name = fmt.Sprintf("entry-%d", i)
+		}
+		if err := writeJSON(filepath.Join(dir, name+".json"), t.ExportProof(list, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	buf, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent(%T): %v", v, err)
+	}
+	if err := os.WriteFile(path, append(buf, '\n'), 0644); err != nil {
+		return fmt.Errorf("os.WriteFile(%q): %v", path, err)
+	}
+	return nil
+}
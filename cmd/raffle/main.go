@@ -0,0 +1,121 @@
+// The raffle binary finds every raffle manifest (raffle.json) in the
+// repository and conducts the draws that they describe. Winners are written to
+// stdout while progress is written to stderr, reproducing the output of the
+// original raffle.sh.
+//
+// With -write_results, the winners of every manifest are also recorded in
+// machine-readable results files in its folder; see raffle.Results. With
+// -check_results, the committed results files are instead compared to the
+// fresh draws, as are any Merkle roots to those of the current lists, failing
+// if any differ.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	root := flag.String("root", ".", "Root of the tree in which to search for raffle manifests.")
+	writeResults := flag.Bool("write_results", false, "Write "+raffle.ResultsFile+" and "+raffle.ResultsCSVFile+" in each manifest's folder.")
+	checkResults := flag.Bool("check_results", false, "Fail if the committed "+raffle.ResultsFile+" or "+raffle.ResultsCSVFile+" of any manifest differs from the fresh draws.")
+	flag.Parse()
+
+	if *writeResults && *checkResults {
+		fmt.Fprintln(os.Stderr, "-write_results and -check_results are mutually exclusive")
+		os.Exit(1)
+	}
+
+	n, err := run(*root, *writeResults, *checkResults)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "%d manifest(s) with mismatched results\n", n)
+		os.Exit(1)
+	}
+}
+
+func stderr(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+}
+
+// run conducts the draws of all manifests under root, returning the number
+// whose committed results don't match if checkResults is true.
+func run(root string, writeResults, checkResults bool) (int, error) {
+	manifests, err := raffle.Find(root)
+	if err != nil {
+		return 0, err
+	}
+
+	var mismatched int
+	divider := strings.Repeat("=", 80)
+	for _, m := range manifests {
+		if checkResults {
+			// Merkle roots may be published before the entropy is known.
+			switch err := m.CheckMerkleRoots(); {
+			case errors.Is(err, raffle.ErrResultsMismatch):
+				fmt.Fprintln(os.Stderr, err)
+				mismatched++
+			case err != nil:
+				return 0, fmt.Errorf("%T.CheckMerkleRoots() in %q: %v", m, m.Dir, err)
+			}
+		}
+
+		results, err := m.Run()
+		if errors.Is(err, raffle.ErrNoEntropy) {
+			stderr("\n%s\n\nDrawing from %s\n\nNo entropy set. Skipping...\n", divider, m.Dir)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%T.Run() in %q: %v", m, m.Dir, err)
+		}
+
+		for _, r := range results {
+			stderr("\n%s\n\nDrawing from %s\n\n", divider, r.List)
+			if r.Tier != "" {
+				stderr("Tier %q\n", r.Tier)
+			}
+			if r.Fold > 0 {
+				stderr("Folding entropy %d time\n", r.Fold)
+			}
+			if r.Label != "" {
+				stderr("Deriving entropy with label %q\n", r.Label)
+			}
+			if r.Draw.Description != "" {
+				stderr("%s\n", r.Draw.Description)
+			}
+			for _, w := range r.Winners {
+				fmt.Println(w)
+			}
+		}
+
+		if !writeResults && !checkResults {
+			continue
+		}
+		rs, err := m.Results(results)
+		if err != nil {
+			return 0, fmt.Errorf("%T.Results() in %q: %v", m, m.Dir, err)
+		}
+		if writeResults {
+			if err := m.WriteResults(rs); err != nil {
+				return 0, fmt.Errorf("%T.WriteResults() in %q: %v", m, m.Dir, err)
+			}
+			continue
+		}
+		switch err := m.CheckResults(rs); {
+		case errors.Is(err, raffle.ErrResultsMismatch):
+			fmt.Fprintln(os.Stderr, err)
+			mismatched++
+		case err != nil:
+			return 0, fmt.Errorf("%T.CheckResults() in %q: %v", m, m.Dir, err)
+		}
+	}
+	return mismatched, nil
+}
@@ -0,0 +1,59 @@
+// The series binary finds every raffle series (series.json) in the repository,
+// conducts the draws of all of its members in order, and reports the winners of
+// more than one member.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	root := flag.String("root", ".", "Root of the tree in which to search for raffle series.")
+	flag.Parse()
+
+	if err := run(*root); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(root string) error {
+	series, err := raffle.FindSeries(root)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range series {
+		results, err := s.Run()
+		if err != nil {
+			return fmt.Errorf("%T.Run() in %q: %v", s, s.Dir, err)
+		}
+
+		policy := s.PreviousWinners
+		if policy == raffle.PreviousWinnersEligible {
+			policy = "eligible"
+		}
+		fmt.Printf("%s: %d of %d members drawn; previous winners %s\n", s.Dir, len(results), len(s.Members), policy)
+
+		repeats := raffle.RepeatWinners(results)
+		winners := make([]string, 0, len(repeats))
+		for w := range repeats {
+			winners = append(winners, w)
+		}
+		sort.Strings(winners)
+
+		for _, w := range winners {
+			fmt.Printf("  %s won %d times: %s\n", w, len(repeats[w]), strings.Join(repeats[w], ", "))
+		}
+		if len(winners) == 0 {
+			fmt.Println("  no repeat winners")
+		}
+	}
+	return nil
+}
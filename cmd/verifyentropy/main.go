@@ -0,0 +1,85 @@
+// The verifyentropy binary checks, offline, that the entropy of every raffle
+// folder is the hash of the block committed to in its entropy file. Block
+// headers, in either JSON or RLP encoding, are read from a file saved next to
+// the entropy file; see entropy.HeaderFiles.
+//
+// To obtain the header of block N, save the response of the JSON-RPC request
+// `eth_getBlockByNumber(hex(N), false)` as headers.json, or the response of
+// `debug_getRawHeader(hex(N))` as headers.rlp. Multiple headers MAY be saved,
+// as an array or one per line respectively, in which case they MUST form a
+// contiguous chain.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/raffle"
+)
+
+func main() {
+	root := flag.String("root", ".", "Root of the tree in which to search for raffle folders.")
+	requireHeaders := flag.Bool("require_headers", false, "Treat a missing header file as a failure.")
+	flag.Parse()
+
+	n, err := run(*root, *requireHeaders)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "%d folder(s) failed verification\n", n)
+		os.Exit(1)
+	}
+}
+
+// run verifies all raffle folders under root, returning the number that
+// failed.
+func run(root string, requireHeaders bool) (int, error) {
+	dirs, err := raffle.Dirs(root)
+	if err != nil {
+		return 0, err
+	}
+
+	var failed int
+	for _, dir := range dirs {
+		e, err := entropy.ParseFile(filepath.Join(dir, entropy.FileName))
+		if err != nil {
+			fmt.Printf("%s: FAIL: %v\n", dir, err)
+			failed++
+			continue
+		}
+		if e.Pending() {
+			fmt.Fprintf(os.Stderr, "%s: pending hash of block %d\n", dir, e.Block)
+			continue
+		}
+
+		headers, err := entropy.FindHeaders(dir)
+		switch {
+		case errors.Is(err, entropy.ErrNoHeaders):
+			if requireHeaders {
+				fmt.Printf("%s: FAIL: no header file\n", dir)
+				failed++
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: unverified; no header file\n", dir)
+			}
+			continue
+		case err != nil:
+			fmt.Printf("%s: FAIL: %v\n", dir, err)
+			failed++
+			continue
+		}
+
+		if err := e.Verify(headers); err != nil {
+			fmt.Printf("%s: FAIL: %v\n", dir, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: OK: block %d hash %v\n", dir, e.Block, e.Hash)
+	}
+	return failed, nil
+}
@@ -3,18 +3,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
 	"sort"
-	"strings"
-	"unsafe"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gocarina/gocsv"
 	"github.com/golang/glog"
-	"github.com/holiman/uint256"
+	"github.com/proofxyz/raffles/seed"
 
 	_ "embed"
 )
@@ -38,14 +39,32 @@ type Transfer struct {
 
 func main() {
 	seedHex := flag.String("seed_hex", fmt.Sprintf("%#x", [32]byte{}), "Hexadecimal seed; at most 256 bits.")
+	prng := flag.String("prng", string(seed.Default), fmt.Sprintf("Pseudo-random number generator expanding the seed; %q reproduces historical results.", seed.Legacy))
+	timeout := flag.Duration("timeout", 0, "If non-zero, stop annealing after this duration, writing the best reallocation found so far as not converged.")
+	maxIterations := flag.Int("max_iterations", 0, "If non-zero, stop annealing after this many iterations, writing the best reallocation found so far as not converged.")
 	flag.Parse()
 
-	if err := run(*seedHex); err != nil {
+	// An interrupt stops annealing, which then writes the best reallocation
+	// found so far. A second interrupt terminates immediately.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if err := run(ctx, *seedHex, *prng, *maxIterations); err != nil {
 		glog.Exit(err)
 	}
 }
 
-func run(seedHex string) error {
+func run(ctx context.Context, seedHex, prngName string, maxIterations int) error {
 	// Load data
 	airdrops := make(map[int]Airdrop)
 	{
@@ -100,27 +119,40 @@ func run(seedHex string) error {
 	glog.Infof("Number per project: %v", initial.numPerProject())
 	glog.Infof("Proportion per project: %.2f", initial.numPerProject().normalised())
 
-	seed, err := foldSeed(seedHex)
+	prng, err := seed.ParsePRNG(prngName)
 	if err != nil {
-		return fmt.Errorf("foldSeed(%q): %v", seedHex, err)
+		return err
 	}
+	key, err := seed.Parse(seedHex)
+	if err != nil {
+		return fmt.Errorf("seed.Parse(%q): %v", seedHex, err)
+	}
+	glog.Infof("Seed %q expanded with %q PRNG", seedHex, prng)
 
-	state := newState(initial, rand.New(rand.NewSource(seed)))
+	state := newState(initial, rand.New(prng.NewSource(key)))
 
 	if err := state.printStats(os.Stderr); err != nil {
 		return fmt.Errorf("%T.printStats(): %v", state, err)
 	}
 
-	if state, err = state.anneal(0.999999, true); err != nil {
+	state, converged, err := state.anneal(ctx, 0.999999, maxIterations, true)
+	if err != nil {
 		return fmt.Errorf("%T.anneal(): %v", state, err)
 	}
+	// Output files of an incomplete run are marked so they can't be mistaken
+	// for the final reallocation.
+	var suffix string
+	if !converged {
+		suffix = "_not-converged"
+		glog.Warningf("NOT CONVERGED (%v); writing best reallocation found so far", notConvergedReason(ctx))
+	}
 
 	if err := state.printStats(os.Stderr); err != nil {
 		return fmt.Errorf("%T.printStats(): %v", state, err)
 	}
 
 	{
-		f, err := os.Create(fmt.Sprintf("overview_%s.csv", seedHex))
+		f, err := os.Create(fmt.Sprintf("overview_%s%s.csv", seedHex, suffix))
 		if err != nil {
 			return fmt.Errorf("os.Create(): %v", err)
 		}
@@ -133,7 +165,7 @@ func run(seedHex string) error {
 	}
 
 	{
-		f, err := os.Create(fmt.Sprintf("reallocations_%s.csv", seedHex))
+		f, err := os.Create(fmt.Sprintf("reallocations_%s%s.csv", seedHex, suffix))
 		if err != nil {
 			return fmt.Errorf("os.Create(): %v", err)
 		}
@@ -145,7 +177,10 @@ func run(seedHex string) error {
 		}
 	}
 
-	if !state.isTrivialOptimum() {
+	switch {
+	case !converged:
+		return fmt.Errorf("annealing did not converge (%v)", notConvergedReason(ctx))
+	case !state.isTrivialOptimum():
 		return fmt.Errorf("final state is not a trivial optimum")
 	}
 
@@ -158,32 +193,15 @@ func run(seedHex string) error {
 	return nil
 }
 
-// foldSeed treats seedHex as a uint256, returning the xor of the 4 uint64s,
-// treating the raw bits as in int64 for use in a rand.Source.
-func foldSeed(seedHex string) (int64, error) {
-	seedHex = strings.TrimPrefix(seedHex, "0x")
-
-	if len(seedHex) > 64 {
-		return 0, fmt.Errorf("hex seed %q longer than 256 bits", seedHex)
-	}
-
-	seedHex = strings.TrimLeft(seedHex, "0")
-	if seedHex == "" {
-		seedHex = "0"
+// notConvergedReason describes why annealing stopped before completing its
+// cooling schedule.
+func notConvergedReason(ctx context.Context) string {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case err != nil:
+		return "interrupted"
+	default:
+		return "max iterations"
 	}
-	seedHex = fmt.Sprintf("0x%s", seedHex)
-
-	ui, err := uint256.FromHex(seedHex)
-	if err != nil {
-		return 0, fmt.Errorf("uint256.FromHex(seed = %q): %v", seedHex, err)
-	}
-
-	var uSeed uint64
-	for _, u := range ([4]uint64)(*ui) {
-		uSeed ^= u
-	}
-
-	seed := *(*int64)(unsafe.Pointer(&uSeed))
-	glog.Infof("Seed %q folded into %#x", seedHex, seed)
-	return seed, nil
 }
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 
-	"github.com/ccssmnn/hego"
+	"github.com/golang/glog"
+	"github.com/proofxyz/raffles/seed"
 )
 
 // state is the state in the simulated annealing algorithm.
@@ -89,44 +91,69 @@ func (s *state) neighbor() *state {
 	return neigh
 }
 
-// Neighbor returns a neighbor of the current state for the simulated annealing process.
-func (s *state) Neighbor() hego.AnnealingState {
-	return s.neighbor()
-}
-
 // Energy returns the energy of the current state for the simulated annealing process.
 func (s *state) Energy() float64 {
 	return -float64(s.cachedScore)
 }
 
 // anneal runs the simulated annealing algorithm on the state.
-func (s *state) anneal(annealingFactor float64, verbose bool) (*state, error) {
-	settings := hego.SASettings{
-		Temperature:     10,
-		AnnealingFactor: annealingFactor,
+//
+// This is an owned reimplementation of hego.SA(), which was originally used but
+// draws its acceptance probabilities from math/rand's global source. That
+// required reseeding with the deprecated rand.Seed(), which newer Go releases
+// make a no-op. The acceptance probabilities are instead drawn from a vendored
+// copy of the same generator, seeded with the value historically passed to
+// rand.Seed(), so results are unchanged.
+//
+// Annealing stops early if ctx is cancelled or after maxIterations, if
+// non-zero and less than the cooling schedule requires. The lowest-energy state
+// seen so far is then returned and converged is false; otherwise the final
+// state is returned, as historically.
+func (s *state) anneal(ctx context.Context, annealingFactor float64, maxIterations int, verbose bool) (_ *state, converged bool, _ error) {
+	const temperature = 10
+	if annealingFactor <= 0 || annealingFactor > 1 {
+		return nil, false, fmt.Errorf("annealing factor must be in (0,1]; got %v", annealingFactor)
+	}
+	if maxIterations < 0 {
+		return nil, false, fmt.Errorf("max iterations must be non-negative; got %d", maxIterations)
 	}
 
 	// Iterations until we reach temp = 1 (which is the minimum energy difference between two neighboring, non-equivalent states)
 	// The probability of accepting a worse state is <= 1/e at this point.
 	// We use this to get an estimate for the amount of iterations needed for cooling.
-	numIterToLukewarm := int(-math.Log(settings.Temperature) / math.Log(float64(settings.AnnealingFactor)))
-
-	settings.Settings.MaxIterations = 2 * numIterToLukewarm
-	if verbose {
-		settings.Settings.Verbose = settings.Settings.MaxIterations / 20
-	}
+	numIterToLukewarm := int(-math.Log(temperature) / math.Log(float64(annealingFactor)))
+	numIterations := 2 * numIterToLukewarm
+
+	accept := rand.New(seed.NewLegacySource(s.rng.Int63()))
+
+	state, energy, temp := s, s.Energy(), float64(temperature)
+	best, bestEnergy := state, energy
+	for i := 0; i < numIterations; i++ {
+		// Checking the context has a non-trivial cost relative to an
+		// iteration, so is only done periodically.
+		if (maxIterations > 0 && i >= maxIterations) || (i%1024 == 0 && ctx.Err() != nil) {
+			if verbose {
+				glog.Warningf("Annealing stopped at iteration %d/%d: temperature=%g energy=%g; best energy=%g", i, numIterations, temp, energy, bestEnergy)
+			}
+			return best, false, nil
+		}
 
-	// Hego's simulated annealing process uses random numbers from the default source.
-	// To get deterministic results we thus reseed that source with the local source.
-	rand.Seed(s.rng.Int63())
+		candidate := state.neighbor()
+		// The order of comparisons MUST NOT change as the random draw is only
+		// made for candidates that aren't strictly better.
+		if e := candidate.Energy(); e < energy || math.Exp((energy-e)/temp) > accept.Float64() {
+			state, energy = candidate, e
+			if e < bestEnergy {
+				best, bestEnergy = state, e
+			}
+		}
+		temp *= annealingFactor
 
-	result, err := hego.SA(s, settings)
-	if err != nil {
-		return nil, err
+		if verbose && i%(numIterations/20+1) == 0 {
+			glog.Infof("Iteration %d/%d: temperature=%g energy=%g", i, numIterations, temp, energy)
+		}
 	}
-
-	finalState := result.State.(*state)
-	return finalState, nil
+	return state, true, nil
 }
 
 type stateStats struct {
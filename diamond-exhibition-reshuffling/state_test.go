@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/proofxyz/raffles/seed"
 )
 
 // ids returns only the token IDs of each token, excluding the project IDs. The
@@ -147,9 +151,9 @@ func TestAnnealStats(t *testing.T) {
 			for seed := int64(0); seed < 25; seed++ {
 				t.Run(fmt.Sprintf("random seed %d", seed), func(t *testing.T) {
 					rng := rand.New(rand.NewSource(seed))
-					s, err := newState(tt.allocations, rng).anneal(tt.annealingFactor, false)
-					if err != nil {
-						t.Errorf("anneal(): err %v", err)
+					s, converged, err := newState(tt.allocations, rng).anneal(context.Background(), tt.annealingFactor, 0, false)
+					if err != nil || !converged {
+						t.Fatalf("anneal(): got (converged = %t, err = %v); want (true, nil)", converged, err)
 					}
 
 					seen := make(map[int]bool)
@@ -193,3 +197,120 @@ func TestAnnealStats(t *testing.T) {
 		})
 	}
 }
+
+func TestAnnealGolden(t *testing.T) {
+	tests := []struct {
+		prng seed.PRNG
+		key  [32]byte
+		// Legacy results were recorded with the original hego.SA()
+		// implementation, which reseeded math/rand's global source.
+		want [][]int
+	}{
+		{
+			prng: seed.Legacy,
+			key:  [32]byte{},
+			want: [][]int{{6, 10}, {11, 13, 16, 17, 19}, {1, 2, 5, 18}, {3, 7, 9, 15}, {0, 4, 8, 12, 14}},
+		},
+		{
+			prng: seed.Legacy,
+			key:  [32]byte{31: 1},
+			want: [][]int{{10, 17}, {8, 12, 13, 16, 19}, {3, 6, 14, 18}, {4, 7, 9, 15}, {0, 1, 2, 5, 11}},
+		},
+		{
+			prng: seed.Legacy,
+			key:  [32]byte{31: 2},
+			want: [][]int{{7, 12}, {14, 16, 17, 18, 19}, {4, 5, 11, 13}, {3, 6, 10, 15}, {0, 1, 2, 8, 9}},
+		},
+		{
+			prng: seed.HMACSHA256,
+			key:  [32]byte{31: 1},
+			want: [][]int{{8, 9}, {7, 12, 14, 16, 19}, {3, 11, 13, 17}, {4, 5, 15, 18}, {0, 1, 2, 6, 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %x", tt.prng, tt.key), func(t *testing.T) {
+			nextFakeTokenId = 0
+			allocs := newAllocationsFromProjectIds([][]int{
+				{1, 1},
+				{2, 2, 2, 3, 3},
+				{3, 3, 4, 4},
+				{4, 4, 1, 1},
+				{1, 2, 3, 4, 0},
+			})
+
+			s, converged, err := newState(allocs, rand.New(tt.prng.NewSource(tt.key))).anneal(context.Background(), 0.999, 0, false)
+			if err != nil || !converged {
+				t.Fatalf("anneal(): got (converged = %t, err = %v); want (true, nil)", converged, err)
+			}
+			var got [][]int
+			for _, c := range s.current {
+				got = append(got, c.tokens.ids())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("anneal() allocated token IDs %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnealStopsEarly(t *testing.T) {
+	newTestState := func() *state {
+		nextFakeTokenId = 0
+		allocs := newAllocationsFromProjectIds([][]int{
+			{1, 1},
+			{2, 2, 2, 3, 3},
+			{3, 3, 4, 4},
+			{4, 4, 1, 1},
+			{1, 2, 3, 4, 0},
+		})
+		return newState(allocs, rand.New(rand.NewSource(0)))
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		maxIterations int
+		wantConverged bool
+	}{
+		{
+			name:          "cancelled",
+			ctx:           cancelled,
+			wantConverged: false,
+		},
+		{
+			name:          "max iterations",
+			ctx:           context.Background(),
+			maxIterations: 500,
+			wantConverged: false,
+		},
+		{
+			name:          "max iterations beyond schedule",
+			ctx:           context.Background(),
+			maxIterations: 1e6,
+			wantConverged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initial := newTestState()
+			s, converged, err := initial.anneal(tt.ctx, 0.999, tt.maxIterations, false)
+			if err != nil {
+				t.Fatalf("anneal(): err %v", err)
+			}
+			if converged != tt.wantConverged {
+				t.Errorf("anneal() got converged %t; want %t", converged, tt.wantConverged)
+			}
+			if s.Energy() > initial.Energy() {
+				t.Errorf("anneal() got energy %g; greater than initial %g", s.Energy(), initial.Energy())
+			}
+			if s.numTokens() != initial.numTokens() {
+				t.Errorf("anneal() got %d tokens; want %d", s.numTokens(), initial.numTokens())
+			}
+		})
+	}
+}
@@ -12,37 +12,153 @@ import (
 // An allocator describes the parameters of a search to allocate the preferences
 // of k entrants to n buckets of variable size.
 type allocator struct {
-	available       []uint64 // size of each choice bucket; dimension n
-	preferences     [][]int  // preferences per entrant; dimension k x n
+	available   []uint64 // size of each choice bucket; dimension n
+	preferences [][]int  // preferences per entrant; dimension k x (up to n)
+	unranked    unrankedPolicy
+
+	// fallbacks are the buckets, per entrant, that weren't ranked, in the
+	// order in which they are tried once all ranked buckets are full; nil
+	// under the noAllocation policy.
+	fallbacks [][]int
+	// unallocatedCost is the cost of an entrant receiving no bucket, and worst
+	// the greatest cost that each entrant can incur.
+	unallocatedCost int
+	worst           []int
 	fittestPossible int
+
+	// metric scores the outcome of each ordering; linear unless changed
+	// after init().
+	metric metric
 }
 
+// An unrankedPolicy determines the treatment of buckets that an entrant didn't
+// rank; i.e. when they provide only their top-k preferences.
+type unrankedPolicy string
+
+const (
+	// requireFullRankings rejects preferences that don't rank every bucket.
+	requireFullRankings unrankedPolicy = ""
+	// tiedLast treats all unranked buckets as tied in last place, falling back
+	// on them in bucket order once all ranked buckets are full.
+	tiedLast unrankedPolicy = "tied"
+	// randomFallback is equivalent to tiedLast except that each entrant tries
+	// the unranked buckets in a random order.
+	randomFallback unrankedPolicy = "random"
+	// noAllocation never allocates an unranked bucket, leaving the entrant
+	// without an allocation once all ranked buckets are full.
+	noAllocation unrankedPolicy = "none"
+)
+
 // init performs sense checks on the allocator and computes the best possible
 // ordering score, whereby every entrant would receive their first preference.
-func (a *allocator) init() error {
+// The src is only used, and therefore MAY otherwise be nil, under the
+// randomFallback policy.
+func (a *allocator) init(src rand.Source) error {
 	// See allocator definition for definition of dimensions.
 	n := len(a.available)
 	k := len(a.preferences)
 
+	switch a.unranked {
+	case requireFullRankings, tiedLast, randomFallback, noAllocation:
+	default:
+		return fmt.Errorf("unknown policy %q for unranked buckets", a.unranked)
+	}
+
+	var (
+		partial  bool
+		capacity uint64
+	)
+	for _, c := range a.available {
+		capacity += c
+	}
+
+	a.fallbacks = make([][]int, k)
 	for i, prefs := range a.preferences {
-		if got, want := len(prefs), n; got != want {
-			return fmt.Errorf("preferences[%d] of length %d; want %d (number of available buckets)", i, got, want)
+		switch got := len(prefs); {
+		case got == 0 || got > n:
+			return fmt.Errorf("preferences[%d] of length %d; want [1,%d] (number of available buckets)", i, got, n)
+		case got < n && a.unranked == requireFullRankings:
+			return fmt.Errorf("preferences[%d] of length %d; want %d (number of available buckets) as no policy for unranked buckets", i, got, n)
+		case got < n:
+			partial = true
 		}
 
-		seen := make([]bool, len(prefs))
+		seen := make([]bool, n)
 		for _, p := range prefs {
+			if p < 0 || p >= n {
+				return fmt.Errorf("preferences[%d] entry %d out of range [0,%d)", i, p, n)
+			}
 			if seen[p] {
 				return fmt.Errorf("preferences[%d] duplicate entry %d", i, p)
 			}
 			seen[p] = true
 		}
+
+		if a.unranked == noAllocation {
+			continue
+		}
+		for p := range seen {
+			if !seen[p] {
+				a.fallbacks[i] = append(a.fallbacks[i], p)
+			}
+		}
 	}
 
-	// Each entrant can be up to (n-1) away from their primary preference.
-	a.fittestPossible = k * (n - 1)
+	if a.unranked == randomFallback && partial {
+		rng := rand.New(src)
+		for _, f := range a.fallbacks {
+			rng.Shuffle(len(f), func(i, j int) {
+				f[i], f[j] = f[j], f[i]
+			})
+		}
+	}
+
+	// Receiving nothing is worse than receiving any bucket, ranked or not.
+	a.unallocatedCost = n
+	canFail := capacity < uint64(k) || (partial && a.unranked == noAllocation)
+
+	a.worst = make([]int, k)
+	for i, prefs := range a.preferences {
+		switch {
+		case canFail:
+			a.worst[i] = a.unallocatedCost
+		case len(prefs) == n:
+			// Each entrant can be up to (n-1) away from their primary
+			// preference.
+			a.worst[i] = n - 1
+		default:
+			// Unranked buckets are tied at the position after the last
+			// ranked one.
+			a.worst[i] = len(prefs)
+		}
+	}
+
+	// The best possible score is the sum of the greatest cost that each
+	// entrant can incur.
+	lin := a.separable(linearPenalty)
+	a.fittestPossible = lin.max
+	a.metric = lin
 	return nil
 }
 
+// choose returns the bucket allocated to the entrant given the number already
+// allocated from each, and the cost of the allocation. If no bucket is
+// available to the entrant, choose returns -1 and unallocatedCost.
+func (a *allocator) choose(entrant int, allocated []uint64) (bucket, cost int) {
+	prefs := a.preferences[entrant]
+	for d, pref := range prefs {
+		if allocated[pref] < a.available[pref] {
+			return pref, d
+		}
+	}
+	for _, b := range a.fallbacks[entrant] {
+		if allocated[b] < a.available[b] {
+			return b, len(prefs)
+		}
+	}
+	return -1, a.unallocatedCost
+}
+
 // islands returns genetic.Islands with a random population of orderings on
 // each. Any existing orderings are included before allocating new random ones.
 // If len(existing)>nOrderings, islands() panics.
@@ -98,33 +214,39 @@ func (o *ordering) swap(i, j int) {
 	o.order[i], o.order[j] = o.order[j], o.order[i]
 }
 
-// Simulate returns the fitness score of the ordering. A perfect score sees
-// every participant receive their first preference. For every step down in
-// allocated preference, the score is reduced by one.
+// Simulate returns the fitness score of the ordering, as computed by the
+// allocator's metric. By default, a perfect score sees every participant
+// receive their first preference. For every step down in allocated preference,
+// the score is reduced by one. An unranked bucket costs one step more than the
+// entrant's last ranked preference, and receiving nothing costs as many steps
+// as there are buckets; see allocator.init().
 //
 // This makes the search effectively a maximiser of utility with constant
 // marginal utility. While allowing entrants to state their utility may have had
 // marginally better results (get it?), this would have complicated the user
-// experience.
+// experience. Alternative metrics, which instead favour the worst-off entrants,
+// are described in metric.go.
 //
 // Cursory profiling shows that this is the greatest contributor to running the
 // algorithm, particular memory allocation. However it completes in ~10 minutes
 // so we haven't performed any optimisation.
+//
+// The context is ignored as a single simulation takes well under a
+// millisecond; the genetic-algorithm library checks it between simulations,
+// which is sufficiently responsive to cancellation.
 func (o *ordering) Simulate(context.Context) float64 {
 	allocated := make([]uint64, len(o.available))
+	profile := make([]int, o.unallocatedCost+1)
 
-	var delta int
 	for _, idx := range o.order {
-		for d, pref := range o.preferences[idx] {
-			if allocated[pref] < o.available[pref] {
-				allocated[pref]++
-				delta += d
-				break
-			}
+		b, d := o.choose(idx, allocated)
+		if b >= 0 {
+			allocated[b]++
 		}
+		profile[d]++
 	}
 
-	return float64(o.fittestPossible - delta)
+	return o.metric.score(profile)
 }
 
 // Functions required by the genetic-algorithm library, which typically has
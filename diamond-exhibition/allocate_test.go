@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnrankedPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		available   []uint64
+		preferences [][]int
+		policy      unrankedPolicy
+		// Entrants choose in index order.
+		wantBuckets     []int // sorted if wantSorted
+		wantSorted      bool
+		wantFittest     int
+		wantFitness     float64
+		wantUnallocated int
+	}{
+		{
+			name:        "full rankings",
+			available:   []uint64{1, 1, 1},
+			preferences: [][]int{{0, 1, 2}, {0, 2, 1}, {0, 1, 2}},
+			wantBuckets: []int{0, 2, 1},
+			wantFittest: 6,
+			wantFitness: 6 - (0 + 1 + 1),
+		},
+		{
+			name:        "full rankings with insufficient capacity",
+			available:   []uint64{1, 1},
+			preferences: [][]int{{0, 1}, {0, 1}, {1, 0}},
+			wantBuckets: []int{0, 1, -1},
+			// Receiving nothing costs 2, as many as there are buckets.
+			wantFittest:     6,
+			wantFitness:     6 - (0 + 1 + 2),
+			wantUnallocated: 1,
+		},
+		{
+			name:        "tied last",
+			available:   []uint64{1, 1, 1},
+			preferences: [][]int{{0}, {0}, {0}},
+			policy:      tiedLast,
+			wantBuckets: []int{0, 1, 2},
+			wantFittest: 3,
+			wantFitness: 3 - (0 + 1 + 1),
+		},
+		{
+			name:        "tied last mixed with full",
+			available:   []uint64{1, 1, 1},
+			preferences: [][]int{{1}, {1, 0, 2}, {1, 2}},
+			policy:      tiedLast,
+			wantBuckets: []int{1, 0, 2},
+			wantFittest: 1 + 2 + 2,
+			wantFitness: 5 - (0 + 1 + 1),
+		},
+		{
+			name:        "random fallback",
+			available:   []uint64{1, 1, 1, 1},
+			preferences: [][]int{{0}, {0}, {0}, {0}},
+			policy:      randomFallback,
+			wantBuckets: []int{0, 1, 2, 3},
+			wantSorted:  true,
+			wantFittest: 4,
+			wantFitness: 4 - (0 + 1 + 1 + 1),
+		},
+		{
+			name:            "no allocation",
+			available:       []uint64{1, 1, 1},
+			preferences:     [][]int{{0}, {0, 1}, {0}},
+			policy:          noAllocation,
+			wantBuckets:     []int{0, 1, -1},
+			wantFittest:     9,
+			wantFitness:     9 - (0 + 1 + 3),
+			wantUnallocated: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &allocator{
+				available:   tt.available,
+				preferences: tt.preferences,
+				unranked:    tt.policy,
+			}
+			if err := a.init(rand.NewSource(42)); err != nil {
+				t.Fatalf("%T.init() error %v", a, err)
+			}
+			if a.fittestPossible != tt.wantFittest {
+				t.Errorf("%T.fittestPossible = %d; want %d", a, a.fittestPossible, tt.wantFittest)
+			}
+
+			o := a.newOrdering()
+			if got := o.Simulate(context.Background()); got != tt.wantFitness {
+				t.Errorf("%T.Simulate() got %v; want %v", o, got, tt.wantFitness)
+			}
+
+			allocated := make([]uint64, len(a.available))
+			var got []int
+			unallocated := 0
+			for _, idx := range o.order {
+				b, _ := a.choose(idx, allocated)
+				if b < 0 {
+					unallocated++
+				} else {
+					allocated[b]++
+				}
+				got = append(got, b)
+			}
+			if tt.wantSorted {
+				sort.Ints(got)
+			}
+			if diff := cmp.Diff(tt.wantBuckets, got); diff != "" {
+				t.Errorf("%T.choose() buckets diff (-want +got):\n%s", a, diff)
+			}
+			if unallocated != tt.wantUnallocated {
+				t.Errorf("%d entrants unallocated; want %d", unallocated, tt.wantUnallocated)
+			}
+		})
+	}
+}
+
+func TestRandomFallbackOrder(t *testing.T) {
+	newAlloc := func(src rand.Source) *allocator {
+		a := &allocator{
+			available:   make([]uint64, 10),
+			preferences: [][]int{{3}, {3, 4}},
+			unranked:    randomFallback,
+		}
+		if err := a.init(src); err != nil {
+			t.Fatalf("%T.init() error %v", a, err)
+		}
+		return a
+	}
+
+	a := newAlloc(rand.NewSource(1))
+	if diff := cmp.Diff(a.fallbacks, newAlloc(rand.NewSource(1)).fallbacks); diff != "" {
+		t.Errorf("fallbacks differ with the same source; diff:\n%s", diff)
+	}
+	for i, f := range a.fallbacks {
+		got := append([]int{}, f...)
+		sort.Ints(got)
+		var want []int
+		for b := 0; b < 10; b++ {
+			if b != 3 && !(i == 1 && b == 4) {
+				want = append(want, b)
+			}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("entrant %d fallbacks aren't a permutation of unranked buckets; diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestAllocatorInitErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences [][]int
+		policy      unrankedPolicy
+		wantErr     string
+	}{
+		{
+			name:        "partial without policy",
+			preferences: [][]int{{0, 1}},
+			wantErr:     "no policy",
+		},
+		{
+			name:        "empty",
+			preferences: [][]int{{}},
+			policy:      tiedLast,
+			wantErr:     "of length 0",
+		},
+		{
+			name:        "too long",
+			preferences: [][]int{{0, 1, 2, 0}},
+			wantErr:     "of length 4",
+		},
+		{
+			name:        "unknown policy",
+			preferences: [][]int{{0, 1, 2}},
+			policy:      "best",
+			wantErr:     "unknown policy",
+		},
+		{
+			name:        "duplicate",
+			preferences: [][]int{{0, 0}},
+			policy:      tiedLast,
+			wantErr:     "duplicate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &allocator{
+				available:   []uint64{1, 1, 1},
+				preferences: tt.preferences,
+				unranked:    tt.policy,
+			}
+			if err := a.init(nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%T.init() got err %v; want containing %q", a, err, tt.wantErr)
+			}
+		})
+	}
+}
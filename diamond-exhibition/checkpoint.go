@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkpointVersion identifies the format of a checkpoint file.
+const checkpointVersion = "proofxyz/raffles/diamond-exhibition.checkpoint/v2"
+
+// checkpointInputs are everything, other than the checkpoint itself, that
+// determines the result of a search. A search can only be resumed with
+// identical inputs.
+type checkpointInputs struct {
+	Seed       string `json:"seed"`
+	PRNG       string `json:"prng"`
+	Metric     string `json:"metric"`
+	Sequential bool   `json:"sequential"`
+
+	Islands         int `json:"islands"`
+	PerIsland       int `json:"perIsland"`
+	Generations     int `json:"generations"`
+	StableThreshold int `json:"stableThreshold"`
+
+	// Allocator is the SHA256 of the available capacities, preferences, and
+	// fallbacks of the allocator.
+	Allocator string `json:"allocator"`
+}
+
+// newCheckpointInputs returns the inputs of the search, other than those
+// describing the seed, PRNG, and metric, which are the responsibility of the
+// caller.
+func newCheckpointInputs(a *allocator, cfg searchConfig) checkpointInputs {
+	buf, err := json.Marshal(struct {
+		Available   []uint64
+		Preferences [][]int
+		Fallbacks   [][]int
+	}{a.available, a.preferences, a.fallbacks})
+	if err != nil {
+		// Implies a bug as all fields can be marshalled.
+		panic(err)
+	}
+	h := sha256.Sum256(buf)
+
+	return checkpointInputs{
+		Sequential:      cfg.sequential,
+		Islands:         cfg.islands,
+		PerIsland:       cfg.perIsland,
+		Generations:     cfg.generations,
+		StableThreshold: cfg.stableThreshold,
+		Allocator:       hex.EncodeToString(h[:]),
+	}
+}
+
+// A checkpoint is the state of a search, from which it can be resumed with
+// the same final result as if it had never been interrupted. Orderings are
+// recorded as their order of entrants.
+type checkpoint struct {
+	Version string           `json:"version"`
+	Inputs  checkpointInputs `json:"inputs"`
+
+	Best        float64 `json:"best"`
+	Champion    []int   `json:"champion"`
+	Individuals [][]int `json:"individuals"`
+
+	Sweeps  int                     `json:"sweeps"`
+	Before  []int                   `json:"before,omitempty"`
+	Next    int                     `json:"next,omitempty"`
+	Evolved []checkpointedEvolution `json:"evolved,omitempty"`
+}
+
+// A checkpointedEvolution is an evolution of the current sweep that is yet to
+// be recorded.
+type checkpointedEvolution struct {
+	Index       int     `json:"index"`
+	Fitness     float64 `json:"fitness"`
+	Profile     []int   `json:"profile,omitempty"`
+	Err         string  `json:"err,omitempty"`
+	Champion    []int   `json:"champion,omitempty"`
+	Individuals [][]int `json:"individuals,omitempty"`
+}
+
+// A checkpointer saves checkpoints of a search to a file.
+type checkpointer struct {
+	path   string
+	inputs checkpointInputs
+}
+
+// save writes a checkpoint of the search. The file is replaced atomically so
+// that an interruption can't leave it corrupted.
+func (c *checkpointer) save(s *search) error {
+	cp := &checkpoint{
+		Version:     checkpointVersion,
+		Inputs:      c.inputs,
+		Best:        s.best,
+		Champion:    orderOf(s.champion),
+		Individuals: ordersOf(s.individuals),
+		Sweeps:      s.sweeps,
+		Before:      s.before,
+		Next:        s.next,
+	}
+	for i := range sweep() {
+		ev, ok := s.evolved[i]
+		if !ok {
+			continue
+		}
+		cev := checkpointedEvolution{
+			Index:       i,
+			Fitness:     ev.fitness,
+			Profile:     ev.profile,
+			Champion:    orderOf(ev.champion),
+			Individuals: ordersOf(ev.individuals),
+		}
+		if ev.err != nil {
+			cev.Err = ev.err.Error()
+		}
+		cp.Evolved = append(cp.Evolved, cev)
+	}
+
+	buf, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%T): %v", cp, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp(): %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%T.Write(): %v", tmp, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%T.Close(): %v", tmp, err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("os.Rename(%q, %q): %v", tmp.Name(), c.path, err)
+	}
+	return nil
+}
+
+// resume restores the search from the checkpoint file, which MUST have been
+// saved with identical inputs.
+func (c *checkpointer) resume(s *search) error {
+	buf, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile(%q): %v", c.path, err)
+	}
+	cp := new(checkpoint)
+	if err := json.Unmarshal(buf, cp); err != nil {
+		return fmt.Errorf("json.Unmarshal(%q, %T): %v", c.path, cp, err)
+	}
+	if cp.Version != checkpointVersion {
+		return fmt.Errorf("%s: checkpoint version %q; want %q", c.path, cp.Version, checkpointVersion)
+	}
+	if cp.Inputs != c.inputs {
+		return fmt.Errorf("%s: checkpoint inputs %+v differ from current %+v", c.path, cp.Inputs, c.inputs)
+	}
+
+	champion, err := s.orderingOf(cp.Champion)
+	if err != nil {
+		return fmt.Errorf("%s: champion: %v", c.path, err)
+	}
+	individuals, err := s.orderingsOf(cp.Individuals)
+	if err != nil {
+		return fmt.Errorf("%s: individuals: %v", c.path, err)
+	}
+	s.best, s.champion, s.individuals = cp.Best, champion, individuals
+	// The profile of the champion is cheap to compute, so isn't checkpointed.
+	s.bestProfile = s.profile(champion.assignment())
+	s.sweeps, s.before, s.next = cp.Sweeps, cp.Before, cp.Next
+
+	s.evolved = nil
+	if len(cp.Evolved) > 0 {
+		s.evolved = make(map[int]evolution)
+	}
+	for _, cev := range cp.Evolved {
+		ev := evolution{fitness: cev.Fitness, profile: cev.Profile}
+		if cev.Err != "" {
+			ev.err = errors.New(cev.Err)
+		}
+		if cev.Champion != nil {
+			if ev.champion, err = s.orderingOf(cev.Champion); err != nil {
+				return fmt.Errorf("%s: evolution %d champion: %v", c.path, cev.Index, err)
+			}
+			if ev.individuals, err = s.orderingsOf(cev.Individuals); err != nil {
+				return fmt.Errorf("%s: evolution %d individuals: %v", c.path, cev.Index, err)
+			}
+		}
+		s.evolved[cev.Index] = ev
+	}
+	return nil
+}
+
+func orderOf(o *ordering) []int {
+	if o == nil {
+		return nil
+	}
+	return o.order
+}
+
+func ordersOf(ords []*ordering) [][]int {
+	orders := make([][]int, len(ords))
+	for i, o := range ords {
+		orders[i] = o.order
+	}
+	return orders
+}
+
+// orderingOf returns an ordering with the order, which MUST be a permutation
+// of all entrants.
+func (a *allocator) orderingOf(order []int) (*ordering, error) {
+	k := len(a.preferences)
+	if len(order) != k {
+		return nil, fmt.Errorf("order of %d entrants; want %d", len(order), k)
+	}
+	seen := make([]bool, k)
+	for _, idx := range order {
+		if idx < 0 || idx >= k || seen[idx] {
+			return nil, fmt.Errorf("order is not a permutation of [0,%d)", k)
+		}
+		seen[idx] = true
+	}
+	o := a.newOrdering()
+	copy(o.order, order)
+	return o, nil
+}
+
+func (a *allocator) orderingsOf(orders [][]int) ([]*ordering, error) {
+	ords := make([]*ordering, len(orders))
+	for i, order := range orders {
+		o, err := a.orderingOf(order)
+		if err != nil {
+			return nil, err
+		}
+		ords[i] = o
+	}
+	return ords, nil
+}
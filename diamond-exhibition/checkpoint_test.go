@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	type result struct {
+		Best   float64
+		Order  []int
+		Sweeps int
+	}
+
+	for _, sequential := range []bool{true, false} {
+		cfg := testSearchConfig
+		cfg.sequential = sequential
+		cfg.workers = 1
+
+		s := testSearch(t, cfg)
+		if _, err := s.run(context.Background()); err != nil {
+			t.Fatalf("sequential = %t: uninterrupted %T.run() error %v", sequential, s, err)
+		}
+		want := result{s.best, s.champion.order, s.sweeps}
+
+		// Copies of the checkpoint are taken before every evolution, each
+		// being a point at which the search could have been interrupted.
+		dir := t.TempDir()
+		path := filepath.Join(dir, "checkpoint.json")
+		inputs := newCheckpointInputs(s.allocator, cfg)
+
+		var snapshots []string
+		s = testSearch(t, cfg)
+		s.checkpoint = &checkpointer{path: path, inputs: inputs}
+		newSrc := s.newSrc
+		s.newSrc = func(sweep, i int) rand.Source {
+			if buf, err := os.ReadFile(path); err == nil {
+				snap := filepath.Join(dir, strings.Repeat("x", len(snapshots)+1))
+				if err := os.WriteFile(snap, buf, 0600); err != nil {
+					t.Fatalf("os.WriteFile(%q) error %v", snap, err)
+				}
+				snapshots = append(snapshots, snap)
+			}
+			return newSrc(sweep, i)
+		}
+		if _, err := s.run(context.Background()); err != nil {
+			t.Fatalf("sequential = %t: checkpointed %T.run() error %v", sequential, s, err)
+		}
+		if len(snapshots) < 2 {
+			t.Fatalf("sequential = %t: %d checkpoint snapshots; test requires multiple", sequential, len(snapshots))
+		}
+
+		for i := 0; i < len(snapshots); i += 7 {
+			r := testSearch(t, cfg)
+			r.checkpoint = &checkpointer{path: snapshots[i], inputs: inputs}
+			if err := r.checkpoint.resume(r); err != nil {
+				t.Fatalf("sequential = %t: resume() from snapshot %d error %v", sequential, i, err)
+			}
+			if _, err := r.run(context.Background()); err != nil {
+				t.Fatalf("sequential = %t: resumed %T.run() error %v", sequential, r, err)
+			}
+			got := result{r.best, r.champion.order, r.sweeps}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("sequential = %t: resumed from snapshot %d; result diff (-uninterrupted +resumed):\n%s", sequential, i, diff)
+			}
+		}
+	}
+}
+
+func TestResumeErrors(t *testing.T) {
+	cfg := testSearchConfig
+	cfg.workers = 1
+	s := testSearch(t, cfg)
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	inputs := newCheckpointInputs(s.allocator, cfg)
+
+	c := &checkpointer{path: path, inputs: inputs}
+	if err := c.resume(s); err == nil {
+		t.Errorf("resume() from missing file got nil error")
+	}
+	if err := c.save(s); err != nil {
+		t.Fatalf("save() error %v", err)
+	}
+
+	changed := inputs
+	changed.Seed = "0x01"
+	c = &checkpointer{path: path, inputs: changed}
+	if err := c.resume(s); err == nil {
+		t.Errorf("resume() with different inputs got nil error")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":"`+checkpointVersion+`","champion":[0,0]}`), 0600); err != nil {
+		t.Fatalf("os.WriteFile(%q) error %v", path, err)
+	}
+	c = &checkpointer{path: path}
+	if err := c.resume(s); err == nil {
+		t.Errorf("resume() with invalid champion got nil error")
+	}
+}
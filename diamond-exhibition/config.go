@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// A config describes the buckets of an allocation and the path to the ranked
+// preferences of entrants.
+type config struct {
+	Description string `json:"description"`
+	// Rankings is the path to a JSON array of rankings, relative to the config
+	// file.
+	Rankings string `json:"rankings"`
+	// TotalCapacity, if non-zero, is checked against the sum of all bucket
+	// capacities as a guard against typos.
+	TotalCapacity uint64   `json:"totalCapacity"`
+	Buckets       []bucket `json:"buckets"`
+	// Unranked is the policy for buckets not ranked by an entrant: "random",
+	// "none", or "tied"; see unrankedPolicy. If empty, all entrants MUST rank
+	// every bucket.
+	Unranked unrankedPolicy `json:"unranked,omitempty"`
+
+	// dir is the directory of the config file.
+	dir string
+}
+
+// A bucket is a single choice, with limited capacity, some of which MAY be
+// reserved for named purposes (e.g. a copy for the artist) and therefore
+// unavailable for allocation.
+type bucket struct {
+	Name         string            `json:"name"`
+	Capacity     uint64            `json:"capacity"`
+	Reservations map[string]uint64 `json:"reservations"`
+}
+
+// available returns the capacity of the bucket less all reservations.
+func (b bucket) available() uint64 {
+	n := b.Capacity
+	for _, r := range b.Reservations {
+		n -= r
+	}
+	return n
+}
+
+// A ranking is the ordered preferences of an entrant, each being an index into
+// config.Buckets. Entrants MAY rank only their top preferences, subject to
+// config.Unranked.
+type ranking struct {
+	Sender   common.Address
+	TokenID  uint64
+	Rankings []int
+}
+
+// loadConfig parses and validates the config at path.
+func loadConfig(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+
+	c := &config{dir: filepath.Dir(path)}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Decode(%q): %v", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+func (c *config) validate() error {
+	if len(c.Buckets) == 0 {
+		return fmt.Errorf("no buckets")
+	}
+	if c.Rankings == "" {
+		return fmt.Errorf("no rankings path")
+	}
+
+	var total uint64
+	names := make(map[string]bool)
+	for i, b := range c.Buckets {
+		if b.Name == "" {
+			return fmt.Errorf("bucket %d without name", i)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("duplicate bucket name %q", b.Name)
+		}
+		names[b.Name] = true
+
+		var reserved uint64
+		for _, r := range b.Reservations {
+			reserved += r
+		}
+		if reserved > b.Capacity {
+			return fmt.Errorf("bucket %q reservations (%d) exceed capacity (%d)", b.Name, reserved, b.Capacity)
+		}
+		total += b.Capacity
+	}
+
+	if c.TotalCapacity != 0 && total != c.TotalCapacity {
+		return fmt.Errorf("total capacity = %d; expecting %d", total, c.TotalCapacity)
+	}
+	return nil
+}
+
+// allocator returns an allocator with the available capacity of each bucket
+// and the preferences of every entrant, as well as the rankings from which the
+// preferences were read. Rankings are sorted by token ID, regardless of their
+// order in the file. The src is passed to allocator.init().
+func (c *config) allocator(src rand.Source) (*allocator, []ranking, error) {
+	path := filepath.Join(c.dir, c.Rankings)
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("os.ReadFile(%q): %v", path, err)
+	}
+	var rankings []ranking
+	if err := json.Unmarshal(buf, &rankings); err != nil {
+		return nil, nil, fmt.Errorf("json.Unmarshal(%q, %T): %v", path, &rankings, err)
+	}
+	// Although the algorithm selects a random ordering of this slice, we want
+	// a deterministic starting position to give repeatable runs.
+	sort.Slice(rankings, func(i, j int) bool {
+		return rankings[i].TokenID < rankings[j].TokenID
+	})
+
+	alloc := &allocator{unranked: c.Unranked}
+	for _, b := range c.Buckets {
+		alloc.available = append(alloc.available, b.available())
+	}
+	for _, entrant := range rankings {
+		alloc.preferences = append(alloc.preferences, append([]int{}, entrant.Rankings...))
+	}
+
+	if err := alloc.init(src); err != nil {
+		return nil, nil, fmt.Errorf("%T.init(): %v", alloc, err)
+	}
+	return alloc, rankings, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiamondExhibitionConfig(t *testing.T) {
+	c, err := loadConfig("config.json")
+	if err != nil {
+		t.Fatalf("loadConfig() error %v", err)
+	}
+	alloc, rankings, err := c.allocator(nil)
+	if err != nil {
+		t.Fatalf("%T.allocator() error %v", c, err)
+	}
+
+	// The capacities originally hardcoded in the binary, less one for each
+	// artist and 10 of Everydays: Group Effort for IRL-event attendees.
+	want := []uint64{599, 599, 599, 799, 999, 599, 449, 999, 255, 999, 999, 89, 99, 99, 1126, 76, 99, 99, 199, 99, 99}
+	if diff := cmp.Diff(want, alloc.available); diff != "" {
+		t.Errorf("%T.available diff (-want +got):\n%s", alloc, diff)
+	}
+
+	if got, want := len(alloc.preferences), len(rankings); got != want {
+		t.Errorf("len(%T.preferences) = %d; want %d", alloc, got, want)
+	}
+	for i := 1; i < len(rankings); i++ {
+		if rankings[i-1].TokenID >= rankings[i].TokenID {
+			t.Fatalf("rankings not sorted by token ID at index %d", i)
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, config, rankings, wantErr string
+	}{
+		{
+			name:    "no buckets",
+			config:  `{"rankings": "r.json"}`,
+			wantErr: "no buckets",
+		},
+		{
+			name:    "unknown field",
+			config:  `{"rankings": "r.json", "unranked": "tied", "totalCapacity": 1, "buckets": [{"name": "a", "capacity": 1, "reservation": {"artist": 1}}]}`,
+			wantErr: `unknown field "reservation"`,
+		},
+		{
+			name:    "duplicate bucket",
+			config:  `{"rankings": "r.json", "buckets": [{"name": "a", "capacity": 1}, {"name": "a", "capacity": 1}]}`,
+			wantErr: "duplicate",
+		},
+		{
+			name:    "over-reserved",
+			config:  `{"rankings": "r.json", "buckets": [{"name": "a", "capacity": 1, "reservations": {"artist": 2}}]}`,
+			wantErr: "exceed capacity",
+		},
+		{
+			name:    "total",
+			config:  `{"rankings": "r.json", "totalCapacity": 3, "buckets": [{"name": "a", "capacity": 1}]}`,
+			wantErr: "total capacity",
+		},
+		{
+			name:     "ranking length",
+			config:   `{"rankings": "r.json", "buckets": [{"name": "a", "capacity": 1}, {"name": "b", "capacity": 1}]}`,
+			rankings: `[{"TokenID": 1, "Rankings": [0]}]`,
+			wantErr:  "of length 1",
+		},
+		{
+			name:     "ranking out of range",
+			config:   `{"rankings": "r.json", "buckets": [{"name": "a", "capacity": 1}]}`,
+			rankings: `[{"TokenID": 1, "Rankings": [1]}]`,
+			wantErr:  "out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatalf("os.WriteFile() error %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "r.json"), []byte(tt.rankings), 0644); err != nil {
+				t.Fatalf("os.WriteFile() error %v", err)
+			}
+
+			c, err := loadConfig(path)
+			if err == nil {
+				_, _, err = c.allocator(nil)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadConfig(%s) then allocator() got err %v; want containing %q", tt.config, err, tt.wantErr)
+			}
+		})
+	}
+}
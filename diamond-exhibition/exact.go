@@ -0,0 +1,230 @@
+package main
+
+import "math"
+
+// An assignment allocates each entrant, by index into allocator.preferences,
+// to a bucket, or to -1 if they receive nothing.
+type assignment []int
+
+// costs returns the cost of allocating each bucket to each entrant, as
+// accounted for by ordering.Simulate(), with -1 denoting buckets that can't be
+// allocated to the entrant.
+func (a *allocator) costs() [][]int {
+	cs := make([][]int, len(a.preferences))
+	for i, prefs := range a.preferences {
+		c := make([]int, len(a.available))
+		for b := range c {
+			c[b] = -1
+		}
+		for d, p := range prefs {
+			c[p] = d
+		}
+		for _, b := range a.fallbacks[i] {
+			c[b] = len(prefs)
+		}
+		cs[i] = c
+	}
+	return cs
+}
+
+// fitness returns the fitness of the assignment, equivalent to that returned
+// by ordering.Simulate() for an ordering resulting in the same assignment.
+func (a *allocator) fitness(asg assignment) float64 {
+	return a.metric.score(a.profile(asg))
+}
+
+// assignment returns the assignment resulting from the ordering.
+func (o *ordering) assignment() assignment {
+	asg := make(assignment, len(o.preferences))
+	allocated := make([]uint64, len(o.available))
+	for _, idx := range o.order {
+		b, _ := o.choose(idx, allocated)
+		if b >= 0 {
+			allocated[b]++
+		}
+		asg[idx] = b
+	}
+	return asg
+}
+
+// optimal returns an assignment with the greatest possible score under the
+// separable metric, regardless of whether it can be achieved by any ordering;
+// see achievable().
+//
+// Minimising the total cost of an assignment, subject to bucket capacities, is
+// a min-cost flow problem, which optimal() solves with successive shortest
+// paths. As there are far fewer buckets than entrants, paths are found over a
+// graph of only the buckets, plus a virtual, uncapacitated bucket for entrants
+// who receive nothing. Adding an entrant to a bucket that is already full
+// requires moving an existing entrant to another bucket, so the weight of the
+// edge from bucket u to v is the lowest change in cost of moving any entrant
+// from u to v. Every path ends at a bucket with spare capacity.
+//
+// Each entrant is added along a shortest path, so the assignment of all
+// entrants added so far is always of minimum cost, and the final assignment
+// optimal.
+func (a *allocator) optimal(m separable) assignment {
+	n := len(a.available)
+	none := n // index of the virtual bucket
+	cs := a.costs()
+	cost := func(entrant, bucket int) int {
+		if bucket == none {
+			return m.of(a.unallocatedCost)
+		}
+		if c := cs[entrant][bucket]; c >= 0 {
+			return m.of(c)
+		}
+		return -1
+	}
+
+	asg := make([]int, len(a.preferences))
+	for i := range asg {
+		asg[i] = -1
+	}
+	allocated := make([]uint64, n+1)
+	spare := func(b int) bool {
+		return b == none || allocated[b] < a.available[b]
+	}
+
+	const inf = math.MaxInt
+	var (
+		weight = make([][]int, n+1) // weight[u][v] of moving an entrant from u to v
+		mover  = make([][]int, n+1) // the entrant achieving weight[u][v]
+		dist   = make([]int, n+1)
+		prev   = make([]int, n+1)
+	)
+	for u := range weight {
+		weight[u] = make([]int, n+1)
+		mover[u] = make([]int, n+1)
+	}
+
+	for added := range asg {
+		for u := range weight {
+			for v := range weight[u] {
+				weight[u][v] = inf
+			}
+		}
+		for x := 0; x < added; x++ {
+			u := asg[x]
+			if u < 0 {
+				u = none
+			}
+			cu := cost(x, u)
+			for v := 0; v <= n; v++ {
+				cv := cost(x, v)
+				if v == u || cv < 0 {
+					continue
+				}
+				if w := cv - cu; w < weight[u][v] {
+					weight[u][v] = w
+					mover[u][v] = x
+				}
+			}
+		}
+
+		// Bellman–Ford from the new entrant, which is connected to every
+		// bucket that they can receive. There are no negative cycles as the
+		// existing assignment is of minimum cost.
+		for v := 0; v <= n; v++ {
+			dist[v], prev[v] = inf, -1
+			if c := cost(added, v); c >= 0 {
+				dist[v] = c
+			}
+		}
+		for iter := 0; iter < n; iter++ {
+			changed := false
+			for u := 0; u <= n; u++ {
+				if dist[u] == inf || spare(u) {
+					// Paths end at the first bucket with spare capacity.
+					continue
+				}
+				for v := 0; v <= n; v++ {
+					if w := weight[u][v]; w != inf && dist[u]+w < dist[v] {
+						dist[v], prev[v] = dist[u]+w, u
+						changed = true
+					}
+				}
+			}
+			if !changed {
+				break
+			}
+		}
+
+		// The virtual bucket is last so real ones are preferred in a tie.
+		end := -1
+		for v := 0; v <= n; v++ {
+			if spare(v) && dist[v] != inf && (end == -1 || dist[v] < dist[end]) {
+				end = v
+			}
+		}
+
+		// Walk back along the path, moving each entrant forward one bucket.
+		// Only the last bucket receives an additional entrant.
+		allocated[end]++
+		first := end
+		for v := end; prev[v] != -1; v = prev[v] {
+			u := prev[v]
+			if x := mover[u][v]; v == none {
+				asg[x] = -1
+			} else {
+				asg[x] = v
+			}
+			first = u
+		}
+		if first == none {
+			asg[added] = -1
+		} else {
+			asg[added] = first
+		}
+	}
+	return asg
+}
+
+// achievable returns an ordering whose assignment, under Simulate(), is as
+// close as possible to asg, and whether it is identical.
+//
+// When every entrant ranks every bucket, the assignments achievable by an
+// ordering are exactly those that are Pareto efficient. An optimal()
+// assignment is always Pareto efficient, as improving the allocation of any
+// entrant without worsening another would reduce its cost, so achievable()
+// always reproduces it. Under an unranked policy the optimal assignment MAY
+// rely on a choice between tied buckets that no ordering makes, in which case
+// the returned ordering is the closest found, not necessarily the best of all
+// orderings.
+func (a *allocator) achievable(asg assignment) (*ordering, bool) {
+	o := a.newOrdering()
+	allocated := make([]uint64, len(a.available))
+	placed := make([]bool, len(asg))
+
+	exact := true
+	for i := range o.order {
+		next := -1
+		for x, done := range placed {
+			if done {
+				continue
+			}
+			if b, _ := a.choose(x, allocated); b == asg[x] {
+				next = x
+				break
+			}
+		}
+		if next == -1 {
+			// No remaining entrant would choose their assigned bucket, so
+			// fall back on the first remaining one.
+			exact = false
+			for x, done := range placed {
+				if !done {
+					next = x
+					break
+				}
+			}
+		}
+
+		if b, _ := a.choose(next, allocated); b >= 0 {
+			allocated[b]++
+		}
+		placed[next] = true
+		o.order[i] = next
+	}
+	return o, exact
+}
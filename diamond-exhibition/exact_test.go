@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// bruteForceOptimum returns the greatest fitness of any assignment that
+// respects bucket capacities.
+func bruteForceOptimum(a *allocator) float64 {
+	cs := a.costs()
+	best := -1.
+	asg := make(assignment, len(a.preferences))
+	allocated := make([]uint64, len(a.available))
+
+	var recurse func(i int)
+	recurse = func(i int) {
+		if i == len(asg) {
+			if f := a.fitness(asg); f > best {
+				best = f
+			}
+			return
+		}
+		asg[i] = -1
+		recurse(i + 1)
+		for b, c := range cs[i] {
+			if c < 0 || allocated[b] == a.available[b] {
+				continue
+			}
+			allocated[b]++
+			asg[i] = b
+			recurse(i + 1)
+			allocated[b]--
+		}
+	}
+	recurse(0)
+	return best
+}
+
+// bestOrdering returns the greatest fitness of any ordering.
+func bestOrdering(a *allocator) float64 {
+	o := a.newOrdering()
+	best := -1.
+	var permute func(i int)
+	permute = func(i int) {
+		if i == len(o.order) {
+			if f := o.Simulate(context.Background()); f > best {
+				best = f
+			}
+			return
+		}
+		for j := i; j < len(o.order); j++ {
+			o.swap(i, j)
+			permute(i + 1)
+			o.swap(i, j)
+		}
+	}
+	permute(0)
+	return best
+}
+
+func randomAllocator(rng *rand.Rand, policy unrankedPolicy) *allocator {
+	n := 1 + rng.Intn(4)
+	k := 1 + rng.Intn(6)
+	a := &allocator{
+		available:   make([]uint64, n),
+		preferences: make([][]int, k),
+		unranked:    policy,
+	}
+	for b := range a.available {
+		a.available[b] = uint64(rng.Intn(3))
+	}
+	for i := range a.preferences {
+		prefs := rng.Perm(n)
+		if policy != requireFullRankings {
+			prefs = prefs[:1+rng.Intn(n)]
+		}
+		a.preferences[i] = prefs
+	}
+	return a
+}
+
+func TestOptimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	for _, pen := range []penalty{linearPenalty, squaredPenalty} {
+		for _, policy := range []unrankedPolicy{requireFullRankings, tiedLast, randomFallback, noAllocation} {
+			for trial := 0; trial < 200; trial++ {
+				a := randomAllocator(rng, policy)
+				if err := a.init(rng); err != nil {
+					t.Fatalf("%T.init() error %v", a, err)
+				}
+				m := a.separable(pen)
+				a.metric = m
+				desc := fmt.Sprintf("penalty %q; policy %q; available %v; preferences %v", pen.name, policy, a.available, a.preferences)
+
+				asg := a.optimal(m)
+				allocated := make([]uint64, len(a.available))
+				for _, b := range asg {
+					if b >= 0 {
+						allocated[b]++
+					}
+				}
+				for b, n := range allocated {
+					if n > a.available[b] {
+						t.Fatalf("%s: optimal() = %v exceeds capacity of bucket %d", desc, asg, b)
+					}
+				}
+
+				got := a.fitness(asg)
+				if want := bruteForceOptimum(a); got != want {
+					t.Errorf("%s: optimal() = %v with fitness %v; brute-force optimum %v", desc, asg, got, want)
+				}
+				if best := bestOrdering(a); best > got {
+					t.Errorf("%s: best ordering fitness %v > optimum %v", desc, best, got)
+				}
+
+				o, exact := a.achievable(asg)
+				if policy == requireFullRankings && !exact {
+					t.Errorf("%s: achievable(%v) not exact with full rankings", desc, asg)
+				}
+				if exact {
+					if diff := cmp.Diff(asg, o.assignment()); diff != "" {
+						t.Errorf("%s: achievable() reported exact but assignment diff (-want +got):\n%s", desc, diff)
+					}
+					if f := o.Simulate(context.Background()); f != got {
+						t.Errorf("%s: achievable() ordering Simulate() = %v; want %v", desc, f, got)
+					}
+				}
+			}
+		}
+	}
+}
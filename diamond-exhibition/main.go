@@ -1,41 +1,68 @@
 // The choices binary optimises allocation of ranked preferences for buckets
-// with limited supply. In its current form it allocates artworks for the PROOF
-// Diamond Exhibition, but can be generalised with minimal effort.
+// with limited supply. The buckets, their capacities, and the path to the
+// rankings are read from the JSON config passed with --config; config.json
+// allocates artworks for the PROOF Diamond Exhibition.
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"math/rand"
 	"os"
-	"sort"
+	"os/signal"
+	"runtime"
+	"strconv"
 	"strings"
-	"unsafe"
+	"text/tabwriter"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/holiman/uint256"
-
-	_ "embed"
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/seed"
 )
 
-//go:embed rankings.json
-var rankingsJSON []byte
-
-type ranking struct {
-	Sender   common.Address
-	TokenID  uint64
-	Rankings [21]int
-}
-
 func main() {
+	configPath := flag.String("config", "", "Path to JSON config describing buckets and rankings.")
 	seedHex := flag.String("seed_hex", "0", "Hexadecimal seed; at most 256 bits.")
+	prng := flag.String("prng", string(seed.Default), fmt.Sprintf("Pseudo-random number generator expanding the seed; %q reproduces historical results.", seed.Legacy))
+	solver := flag.String("solver", geneticSolver, fmt.Sprintf("Optimisation algorithm; %q searches over orderings and %q solves for the optimal assignment.", geneticSolver, exactSolver))
+	mechanism := flag.String("mechanism", optimisedMechanism, fmt.Sprintf("Allocation mechanism; one of %q.", mechanismNames))
+	compare := flag.Bool("compare", false, fmt.Sprintf("Compare all mechanisms side by side instead of allocating, solving the %q mechanism exactly.", optimisedMechanism))
+	metric := flag.String("metric", linearMetric, fmt.Sprintf("Fitness metric to maximise; one of %q.", metricNames))
+	achievable := flag.Bool("achievable", false, fmt.Sprintf("With --solver=%s, restrict the assignment to one achieved by an ordering.", exactSolver))
+	trade := flag.Bool("trade", false, "Improve the final allocation with a top-trading-cycles pass, printing each trade.")
+	sequential := flag.Bool("sequential", false, "Evolve one set of genetic-algorithm parameters at a time, as for the historical allocation.")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of sets of genetic-algorithm parameters to evolve concurrently; doesn't affect the result.")
+	checkpointPath := flag.String("checkpoint", "", "Path to which the state of the genetic search is periodically saved.")
+	resume := flag.Bool("resume", false, "Resume the genetic search from --checkpoint, giving the same result as an uninterrupted run.")
+	timeout := flag.Duration("timeout", 0, "If non-zero, stop the genetic search after this duration, reporting the best allocation found so far as not converged.")
+	maxSweeps := flag.Int("max_sweeps", 0, "If non-zero, stop the genetic search after this many parameter sweeps, reporting the best allocation found so far as not converged.")
 	printErrs := flag.Bool("print_errs", false, "Print errors in full.")
 	flag.Parse()
 
-	if err := run(context.Background(), *seedHex, *printErrs); err != nil {
+	searchCfg := defaultSearchConfig
+	searchCfg.sequential = *sequential
+	searchCfg.workers = *workers
+	searchCfg.checkpoint = *checkpointPath
+	searchCfg.resume = *resume
+	searchCfg.maxSweeps = *maxSweeps
+
+	// An interrupt stops the genetic search, which then reports the best
+	// allocation found so far. A second interrupt terminates immediately.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if err := run(ctx, *configPath, *seedHex, *prng, *mechanism, *solver, *metric, searchCfg, *achievable, *trade, *compare, *printErrs); err != nil {
 		stderr("%v\n", err)
 		os.Exit(1)
 	}
@@ -49,212 +76,306 @@ func stderrLn(format string, a ...interface{}) {
 	stderr(format+"\n", a...)
 }
 
-func run(ctx context.Context, seedHex string, printErrs bool) error {
-	var rankings []ranking
-	if err := json.NewDecoder(bytes.NewReader(rankingsJSON)).Decode(&rankings); err != nil {
-		return fmt.Errorf("json.Decoder.Decode(…, %T): %v", &rankings, err)
-	}
-	// Although the algorithm selects a random ordering of this slice, we want
-	// a deterministic starting position to give repeatable runs.
-	sort.Slice(rankings, func(i, j int) bool {
-		return rankings[i].TokenID < rankings[j].TokenID
-	})
-
-	alloc := allocator{
-		available: []uint64{
-			600,  // Impossible Distance				0
-			600,  // cathedral study					1
-			600,  // Deja Vu							2
-			800,  // WaveShapes							3
-			1000, // Ephemeral Tides					4
-			600,  // StackSlash							5
-			450,  // Viridaria							6
-			1000, // Windwoven							7
-			256,  // Memory Loss						8
-			1000, // The Collector's Room				9
-			1000, // Extrañezas							10
-			100,  // Everydays: Group Effort			11
-			100,  // Kid Heart							12
-			100,  // BEHEADED (SELF PORTRAIT)			13
-			1127, // End Transmissions					14
-			77,   // DES CHOSES™						15
-			100,  // A Wintry Night in Chinatown		16
-			100,  // Penthouse							17
-			200,  // Hands of Umbra						18
-			100,  // Solitaire							19
-			100,  // Remnants of a Distant Dream		20
-		},
-	}
-	var total uint64
-	for i := range alloc.available {
-		total += alloc.available[i]
-		alloc.available[i]-- // for the artist
-	}
-	if want := uint64(10_010); total != want {
-		return fmt.Errorf("total availabe = %d; expecting %d", total, want)
-	}
-	alloc.available[11] -= 10 // allocated to IRL-event attendees
-
-	for _, entrant := range rankings {
-		var prefs []int
-		for _, r := range entrant.Rankings {
-			prefs = append(prefs, int(r))
-		}
-		alloc.preferences = append(alloc.preferences, prefs)
-	}
+// Solvers accepted by the --solver flag.
+const (
+	geneticSolver = "genetic"
+	exactSolver   = "exact"
+)
 
-	if err := alloc.init(); err != nil {
-		return fmt.Errorf("%T.init(): %v", alloc, err)
+func run(ctx context.Context, configPath, seedHex, prngName, mechanism, solver, metricName string, searchCfg searchConfig, achievable, tradeCycles, compare, printErrs bool) error {
+	if configPath == "" {
+		return fmt.Errorf("--config flag not specified")
+	}
+	if searchCfg.maxSweeps < 0 {
+		return fmt.Errorf("--max_sweeps must be non-negative; got %d", searchCfg.maxSweeps)
+	}
+	if searchCfg.workers < 1 {
+		return fmt.Errorf("--workers must be positive")
+	}
+	if searchCfg.resume && searchCfg.checkpoint == "" {
+		return fmt.Errorf("--resume requires --checkpoint")
+	}
+	if solver != geneticSolver && solver != exactSolver {
+		return fmt.Errorf("unknown --solver %q; must be %q or %q", solver, geneticSolver, exactSolver)
+	}
+	switch mechanism {
+	case optimisedMechanism, serialDictatorshipMechanism, probabilisticSerialMechanism:
+	default:
+		return fmt.Errorf("unknown --mechanism %q; must be one of %q", mechanism, mechanismNames)
+	}
+	prng, err := seed.ParsePRNG(prngName)
+	if err != nil {
+		return err
+	}
+	key, err := seed.Parse(seedHex)
+	if err != nil {
+		return err
 	}
 
-	seed, err := foldSeed(seedHex)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	// The random order in which unranked buckets are tried is independent of
+	// the search.
+	fallbackKey := entropy.Derive(key, "unranked-fallback")
+	alloc, rankings, err := cfg.allocator(prng.NewSource(fallbackKey))
 	if err != nil {
 		return err
 	}
 	newSrc := func() rand.Source {
-		return rand.NewSource(seed)
+		return prng.NewSource(key)
+	}
+	// The lottery draw is independent of the ordering.
+	lotterySrc := func() rand.Source {
+		return prng.NewSource(entropy.Derive(key, "probabilistic-serial"))
 	}
 
+	m, err := alloc.newMetric(metricName)
+	if err != nil {
+		return err
+	}
+	alloc.metric = m
+	// Only separable metrics can be optimised exactly.
+	sep, exactable := m.(separable)
+
 	// start provides a benchmark of performance had we simply shuffled all
 	// entrants but not performed any optimisation.
-	start := alloc.newOrderings(1, newSrc())[0].Simulate(ctx)
-
-	best := start
-	var champion *ordering
-	// individuals are the full set of orderings from previous optimisations,
-	// fed into the next run so we don't start from a blank slate. This
-	// maintains a diverse range of high-performing orderings whereas only
-	// feeding in the best would favour local minima.
-	var individuals []*ordering
-
-	const (
-		// A number of "islands", each with a "population" are bred for a set
-		// number of "generations", after which the best performing orderings of
-		// the islands "migrate". If this fails to produce an improved fitness
-		// after a threshold number of rounds, it is considered stable and no
-		// further optimisation is performed.
-		nIslands        = 20
-		perIsland       = 10
-		generations     = 50
-		stableThreshold = 15
-	)
-
-	// Mutation rate and polygamy are parameters used by the genetic algorithm.
-	// Instead of locking in a single set of parameters, we perform a sweep of
-	// a range.
-	type params struct {
-		mutRate  float64
-		polygamy int
-	}
-
-	logProgress := func(p params) {
-		delta := best - start
+	random := alloc.newOrderings(1, newSrc())[0]
+	start := random.Simulate(ctx)
+
+	if compare {
+		if !exactable {
+			return fmt.Errorf("--compare only supports the %q and %q metrics", linearMetric, squaredMetric)
+		}
+		return compareMechanisms(alloc, alloc.optimal(sep), newSrc(), lotterySrc())
+	}
+
+	switch mechanism {
+	case serialDictatorshipMechanism:
+		o := alloc.randomSerialDictatorship(newSrc())
+		report(alloc, rankings, o.order, o.assignment(), tradeCycles)
+		return nil
+	case probabilisticSerialMechanism:
+		asg, w, n, err := alloc.draw(alloc.probabilisticSerial(), lotterySrc())
+		if err != nil {
+			return err
+		}
+		stderrLn("Drew assignment %d, with probability %g, from the lottery decomposition of probabilistic serial", n, w)
+		report(alloc, rankings, identity(len(asg)), asg, tradeCycles)
+		return nil
+	}
+
+	if solver == exactSolver {
+		if !exactable {
+			return fmt.Errorf("--solver=%s only supports the %q and %q metrics", exactSolver, linearMetric, squaredMetric)
+		}
+		return solveExact(alloc, rankings, alloc.optimal(sep), start, achievable, tradeCycles)
+	}
+
+	s := &search{
+		allocator: alloc,
+		cfg:       searchCfg,
+		best:      start,
+		// Fall back on the benchmark if the search never improves on it.
+		champion:    random,
+		bestProfile: alloc.profile(random.assignment()),
+		printErrs:   printErrs,
+	}
+	if searchCfg.sequential {
+		s.newSrc = func(int, int) rand.Source {
+			return newSrc()
+		}
+	} else {
+		// Each set of params, in each sweep, is evolved independently.
+		s.newSrc = func(sweep, i int) rand.Source {
+			return prng.NewSource(entropy.Derive(key, fmt.Sprintf("search/%d/%d", sweep, i)))
+		}
+	}
+
+	if searchCfg.checkpoint != "" {
+		inputs := newCheckpointInputs(alloc, searchCfg)
+		inputs.Seed = fmt.Sprintf("%#x", key)
+		inputs.PRNG = string(prng)
+		inputs.Metric = metricName
+		s.checkpoint = &checkpointer{path: searchCfg.checkpoint, inputs: inputs}
+
+		if searchCfg.resume {
+			if err := s.checkpoint.resume(s); err != nil {
+				return err
+			}
+			stderrLn("Resumed from %s after %d sweeps", searchCfg.checkpoint, s.sweeps)
+		}
+	}
+
+	s.onImprove = func(p params) {
+		if _, ok := m.(lexicographic); ok {
+			// Scores MAY be rounded, and only their order is meaningful.
+			stderrLn("\n%s %+v", m.describe(s.bestProfile), p)
+			return
+		}
+		delta := s.best - start
+		if !exactable {
+			// Scores of other metrics aren't relative to a maximum, nor
+			// meaningful per entrant.
+			stderrLn("\n%0.f (+%0.f) %+v", s.best, delta, p)
+			return
+		}
 		stderrLn(
 			"\n%0.f/%d (+%0.f = %.2f%%) %+v",
-			best, alloc.fittestPossible,
+			s.best, sep.max,
 			delta, delta/float64(len(alloc.preferences))*100,
 			p,
 		)
 	}
-
-	var p params
-	logProgress(params{})
+	s.onImprove(params{})
 
 	// The algorithm ends when a full parameter sweep is unable to improve on
 	// best fitness.
-	for before := 0.; before < best; {
-		before = best
-		stderr("|") // progress indicator for a new sweep
-
-		for p.mutRate = 1.; p.mutRate > 0.001; p.mutRate /= 1.5 {
-			for p.polygamy = 0; p.polygamy <= 3; p.polygamy++ {
-				func() {
-					defer func() {
-						// The mu8 package uses panics in some places instead of
-						// bubbling up errors. To simplify reporting, we do the
-						// same.
-						if r := recover(); r != nil {
-							if printErrs {
-								stderrLn("\n%v", r)
-							} else {
-								// Some parameters and starting conditions result in non-fatal
-								// errors, which provide little information when occurring only
-								// sporadically. If too many # appear, run again with the
-								// --print_errs flag.
-								stderr("#") // progress indicator for a failed optimisation
-							}
-						}
-					}()
-
-					islands := alloc.islands(nIslands, nIslands*perIsland, newSrc(), individuals...)
-					var (
-						last   float64
-						stable int
-					)
-					for stable < stableThreshold {
-						if err := islands.Advance(ctx, p.mutRate, p.polygamy, generations, nIslands); err != nil {
-							panic(err)
-						}
-						islands.Crossover() // inter-island migration
-
-						if fit := islands.ChampionFitness(); fit == last {
-							stable++
-						} else {
-							stable = 0
-							last = fit
-						}
-					}
-
-					if f := islands.ChampionFitness(); f > best {
-						best = f
-						logProgress(p)
-
-						champion = islands.Champion()
-						individuals = []*ordering{}
-						for _, p := range islands.Populations() {
-							individuals = append(individuals, p.Individuals()...)
-						}
-					} else {
-						stderr(".") // progress indicator for a successful but weaker optimisation
-					}
-				}()
-			}
+	converged, err := s.run(ctx)
+	if err != nil {
+		return err
+	}
+	best, champion := s.best, s.champion
+	if !converged {
+		reason := notConvergedReason(ctx)
+		stderrLn("\nNOT CONVERGED (%s) after %d sweeps; reporting best allocation found so far", reason, s.sweeps)
+		if searchCfg.checkpoint != "" {
+			stderrLn("Continue the search with --resume")
 		}
+		fmt.Printf("# not converged: %s\n", reason)
 	}
 
-	o := champion
-	allocated := make([]uint64, len(o.available))
-	for _, idx := range o.order {
-		for _, pref := range o.preferences[idx] {
-			if allocated[pref] < o.available[pref] {
-				allocated[pref]++
-				fmt.Println(rankings[idx].Sender, pref)
-				break
-			}
-		}
+	if exactable {
+		// The optimal assignment is an upper bound on the fitness of any
+		// ordering, unlike fittestPossible, which is typically unachievable.
+		optimum := alloc.fitness(alloc.optimal(sep))
+		gap := optimum - best
+		stderrLn(
+			"\nExact optimum %0.f; optimality gap of search %0.f (%.2f per entrant)",
+			optimum, gap, gap/float64(len(alloc.preferences)),
+		)
 	}
 
+	stderrLn("")
+	report(alloc, rankings, champion.order, champion.assignment(), tradeCycles)
 	return nil
 }
 
-// foldSeed treats seedHex as a uint256, returning the xor of the 4 uint64s,
-// treating the raw bits as in int64 for use in a rand.Source.
-func foldSeed(seedHex string) (int64, error) {
-	if !strings.HasPrefix(seedHex, "0x") {
-		seedHex = fmt.Sprintf("0x%s", seedHex)
+// notConvergedReason describes why a search stopped before converging.
+func notConvergedReason(ctx context.Context) string {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case err != nil:
+		return "interrupted"
+	default:
+		return "max sweeps"
 	}
-	if len(seedHex) > 2+64 {
-		return 0, fmt.Errorf("hex seed %q longer than 256 bits", seedHex)
+}
+
+// solveExact prints the optimal assignment, optionally restricted to one that
+// can be achieved by an ordering.
+func solveExact(alloc *allocator, rankings []ranking, optimal assignment, start float64, achievable, tradeCycles bool) error {
+	optimum := alloc.fitness(optimal)
+	stderrLn("%0.f (+%0.f over random ordering) exact optimum", optimum, optimum-start)
+
+	if !achievable {
+		report(alloc, rankings, identity(len(optimal)), optimal, tradeCycles)
+		return nil
 	}
 
-	int, err := uint256.FromHex(seedHex)
+	o, exact := alloc.achievable(optimal)
+	asg := o.assignment()
+	if exact {
+		stderrLn("Achieved by an ordering")
+	} else {
+		f := alloc.fitness(asg)
+		stderrLn("Optimum not achieved by any ordering found; closest %0.f (gap %0.f)", f, optimum-f)
+	}
+	report(alloc, rankings, o.order, asg, tradeCycles)
+	return nil
+}
+
+// report optionally improves the assignment with a top-trading-cycles pass,
+// summarises it under every metric, and prints it in the order specified.
+func report(alloc *allocator, rankings []ranking, order []int, asg assignment, tradeCycles bool) {
+	if tradeCycles {
+		var trades []trade
+		asg, trades = alloc.tradeCycles(asg)
+		for i, t := range trades {
+			var moves []string
+			for _, m := range t {
+				moves = append(moves, fmt.Sprintf("token %d %s -> %s", rankings[m.entrant].TokenID, bucketName(m.from), bucketName(m.to)))
+			}
+			stderrLn("Trade %d: %s", i+1, strings.Join(moves, "; "))
+		}
+		stderrLn("%d trades made", len(trades))
+	}
+	stderrLn("Pareto efficient with respect to rankings: %t", alloc.paretoEfficient(asg))
+	stderrLn("%s", alloc.describeAll(asg))
+	printAllocation(rankings, order, asg)
+}
+
+func bucketName(b int) string {
+	if b < 0 {
+		return "unallocated"
+	}
+	return strconv.Itoa(b)
+}
+
+// identity returns the order of entrants as they appear in the rankings.
+func identity(k int) []int {
+	order := make([]int, k)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// compareMechanisms prints a summary of the allocation of every mechanism,
+// with the optimised one achieved from the optimal assignment.
+func compareMechanisms(alloc *allocator, optimal assignment, orderSrc, lotterySrc rand.Source) error {
+	o, _ := alloc.achievable(optimal)
+	ps, _, _, err := alloc.draw(alloc.probabilisticSerial(), lotterySrc)
 	if err != nil {
-		return 0, fmt.Errorf("uint256.FromHex(seed = %q): %v", seedHex, err)
+		return err
+	}
+	asgs := map[string]assignment{
+		optimisedMechanism:           o.assignment(),
+		serialDictatorshipMechanism:  alloc.randomSerialDictatorship(orderSrc).assignment(),
+		probabilisticSerialMechanism: ps,
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "metric\t"+strings.Join(mechanismNames, "\t"))
+	for _, name := range metricNames {
+		m, err := alloc.newMetric(name)
+		if err != nil {
+			return err
+		}
+		row := []string{name}
+		for _, mech := range mechanismNames {
+			row = append(row, m.describe(alloc.profile(asgs[mech])))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	row := []string{"pareto efficient"}
+	for _, mech := range mechanismNames {
+		row = append(row, strconv.FormatBool(alloc.paretoEfficient(asgs[mech])))
 	}
+	fmt.Fprintln(w, strings.Join(row, "\t"))
+	return w.Flush()
+}
 
-	var seed uint64
-	for _, u := range ([4]uint64)(*int) {
-		seed ^= u
+// printAllocation prints the sender and allocated bucket of each entrant, in
+// the order specified.
+func printAllocation(rankings []ranking, order []int, asg assignment) {
+	for _, idx := range order {
+		if b := asg[idx]; b < 0 {
+			fmt.Println(rankings[idx].Sender, "unallocated")
+		} else {
+			fmt.Println(rankings[idx].Sender, b)
+		}
 	}
-	return *(*int64)(unsafe.Pointer(&seed)), nil
 }
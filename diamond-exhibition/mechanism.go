@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Mechanisms accepted by the --mechanism flag.
+const (
+	// optimisedMechanism searches for the ordering of entrants that maximises
+	// the metric, with the --solver. As the ordering depends on every ranking,
+	// it isn't strategy-proof in general: an entrant's ranking influences
+	// their own position in the ordering.
+	optimisedMechanism = "optimised"
+	// serialDictatorshipMechanism is random serial dictatorship.
+	serialDictatorshipMechanism = "rsd"
+	// probabilisticSerialMechanism is probabilistic serial, drawn from its
+	// lottery decomposition.
+	probabilisticSerialMechanism = "ps"
+)
+
+// mechanismNames are all mechanisms, in the order in which they are compared.
+var mechanismNames = []string{optimisedMechanism, serialDictatorshipMechanism, probabilisticSerialMechanism}
+
+// randomSerialDictatorship returns a single, uniformly random ordering, in
+// which entrants choose without any optimisation. It is strategy-proof: no
+// entrant can receive a better bucket by misreporting their rankings, as the
+// order in which they choose is independent of all rankings.
+//
+// This is the same ordering used as the benchmark for the genetic search,
+// given the same src.
+func (a *allocator) randomSerialDictatorship(src rand.Source) *ordering {
+	return a.newOrderings(1, src)[0]
+}
+
+// A randomAssignment is the probability, p[i][b], of each entrant i receiving
+// bucket b. The final column is the probability of receiving nothing.
+type randomAssignment [][]float64
+
+// epsilon is the tolerance of floating-point comparisons in probabilistic
+// serial and its lottery decomposition.
+const epsilon = 1e-9
+
+// eatingOrder returns the buckets in the order that the entrant would choose
+// them; i.e. ranked buckets followed by fallbacks.
+func (a *allocator) eatingOrder(entrant int) []int {
+	return append(append([]int{}, a.preferences[entrant]...), a.fallbacks[entrant]...)
+}
+
+// probabilisticSerial returns the random assignment of the simultaneous eating
+// algorithm (Bogomolnaia & Moulin, 2001), generalised to buckets of any
+// capacity. All entrants "eat" their most preferred bucket with remaining
+// supply, at a rate of one unit per unit of time, for a single unit of time.
+// The random assignment is ordinally efficient and envy-free: no entrant
+// prefers the lottery of another, in the sense of first-order stochastic
+// dominance. Unlike random serial dictatorship, it is only weakly
+// strategy-proof.
+func (a *allocator) probabilisticSerial() randomAssignment {
+	n := len(a.available)
+	p := make(randomAssignment, len(a.preferences))
+	orders := make([][]int, len(a.preferences))
+	for i := range p {
+		p[i] = make([]float64, n+1)
+		orders[i] = a.eatingOrder(i)
+	}
+	supply := make([]float64, n)
+	for b, c := range a.available {
+		supply[b] = float64(c)
+	}
+
+	eating := make([]int, len(p))
+	for t := 0.; t < 1-epsilon; {
+		eaters := make([]int, n)
+		for i, order := range orders {
+			eating[i] = -1
+			for len(order) > 0 && supply[order[0]] < epsilon {
+				order = order[1:]
+			}
+			if orders[i] = order; len(order) > 0 {
+				eating[i] = order[0]
+				eaters[order[0]]++
+			}
+		}
+
+		dt := 1 - t
+		for b, m := range eaters {
+			if m > 0 {
+				dt = math.Min(dt, supply[b]/float64(m))
+			}
+		}
+		for i, b := range eating {
+			if b >= 0 {
+				p[i][b] += dt
+			}
+		}
+		for b, m := range eaters {
+			supply[b] -= dt * float64(m)
+		}
+		t += dt
+	}
+
+	for i := range p {
+		var sum float64
+		for _, q := range p[i][:n] {
+			sum += q
+		}
+		if none := 1 - sum; none > epsilon {
+			p[i][n] = none
+		}
+	}
+	return p
+}
+
+// decompose decomposes the random assignment into a lottery over
+// deterministic assignments, calling visit with each assignment and its
+// probability, until all probability is exhausted or visit returns false.
+//
+// The set of random assignments, with rows summing to one and columns to no
+// more than bucket capacities, has integer vertices (Budish et al., 2013).
+// Each step finds a deterministic assignment, x, that satisfies every
+// constraint that is tight under the remaining assignment, q, and removes as
+// much of x from q as possible. This makes at least one more constraint tight,
+// so the lottery has at most as many assignments as there are non-zero
+// entries and buckets.
+func (a *allocator) decompose(p randomAssignment, visit func(weight float64, asg assignment) bool) error {
+	n := len(a.available)
+	none := n
+	capacity := func(b int) float64 {
+		if b == none {
+			return float64(len(p))
+		}
+		return float64(a.available[b])
+	}
+
+	// q is the remaining assignment, unnormalised, with total mass of each row
+	// equal to mass.
+	q := make(randomAssignment, len(p))
+	sums := make([]float64, n+1)
+	for i, row := range p {
+		q[i] = append([]float64{}, row...)
+		for b, v := range row {
+			sums[b] += v
+		}
+	}
+
+	m := newDecompositionMatching(a, q)
+	for mass := 1.; mass > epsilon; {
+		tight := make([]bool, n+1)
+		for b, s := range sums {
+			tight[b] = capacity(b)*mass-s < epsilon
+		}
+		if err := m.repair(q, tight); err != nil {
+			return err
+		}
+
+		w := mass
+		for i, row := range q {
+			for b, v := range row {
+				switch {
+				case m.asg[i] == b:
+					w = math.Min(w, v)
+				case v > epsilon:
+					w = math.Min(w, mass-v)
+				}
+			}
+		}
+		for b := range sums {
+			if x := float64(m.count[b]); x < capacity(b) {
+				w = math.Min(w, (capacity(b)*mass-sums[b])/(capacity(b)-x))
+			}
+		}
+
+		if !visit(w, m.assignment()) {
+			return nil
+		}
+		for i, b := range m.asg {
+			q[i][b] -= w
+			sums[b] -= w
+		}
+		mass -= w
+	}
+	return nil
+}
+
+// draw returns an assignment drawn from the lottery decomposition of p, its
+// probability, and the number of assignments in the lottery up to and
+// including it.
+func (a *allocator) draw(p randomAssignment, src rand.Source) (assignment, float64, int, error) {
+	u := rand.New(src).Float64()
+
+	var (
+		drawn  assignment
+		weight float64
+		n      int
+		cum    float64
+	)
+	err := a.decompose(p, func(w float64, asg assignment) bool {
+		drawn, weight = asg, w
+		n++
+		cum += w
+		// If rounding leaves u beyond the total weight, the last assignment
+		// is drawn.
+		return cum <= u
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return drawn, weight, n, nil
+}
+
+// A decompositionMatching is a deterministic assignment, within the support
+// of a random assignment, maintained across the steps of decompose(). The
+// virtual bucket, n, denotes receiving nothing.
+type decompositionMatching struct {
+	n     int
+	cap   []int
+	asg   []int
+	count []int
+}
+
+func newDecompositionMatching(a *allocator, q randomAssignment) *decompositionMatching {
+	n := len(a.available)
+	m := &decompositionMatching{
+		n:     n,
+		cap:   make([]int, n+1),
+		asg:   make([]int, len(q)),
+		count: make([]int, n+1),
+	}
+	for b, c := range a.available {
+		m.cap[b] = int(c)
+	}
+	m.cap[n] = len(q)
+	for i := range m.asg {
+		m.asg[i] = -1
+	}
+	return m
+}
+
+// assignment returns the matching as an assignment.
+func (m *decompositionMatching) assignment() assignment {
+	asg := make(assignment, len(m.asg))
+	for i, b := range m.asg {
+		if b == m.n {
+			b = -1
+		}
+		asg[i] = b
+	}
+	return asg
+}
+
+// repair updates the matching such that every entrant is matched to a bucket
+// in the support of q, and every tight bucket is full.
+func (m *decompositionMatching) repair(q randomAssignment, tight []bool) error {
+	// An entrant certain to receive a bucket has no other in their support.
+	support := func(i, b int) bool {
+		return q[i][b] > epsilon
+	}
+	for i, b := range m.asg {
+		if b != -1 && !support(i, b) {
+			m.count[b]--
+			m.asg[i] = -1
+		}
+	}
+
+	// Match every entrant, directly if possible, otherwise along an augmenting
+	// path ending at a bucket with spare capacity.
+	spare := func(b int) bool {
+		return m.count[b] < m.cap[b]
+	}
+	for i, b := range m.asg {
+		if b != -1 {
+			continue
+		}
+		for b := range m.cap {
+			if support(i, b) && spare(b) {
+				m.asg[i] = b
+				m.count[b]++
+				break
+			}
+		}
+		if m.asg[i] == -1 && !m.augment(i, support, spare) {
+			return fmt.Errorf("no bucket available to entrant %d in the support of the random assignment", i)
+		}
+	}
+
+	// Fill every tight bucket by moving an entrant into it, along a path from
+	// a bucket that isn't tight.
+	for b := range tight {
+		for tight[b] && m.count[b] < m.cap[b] {
+			if !m.pull(b, support, func(u int) bool { return !tight[u] }) {
+				return fmt.Errorf("unable to fill tight bucket %d", b)
+			}
+		}
+	}
+	return nil
+}
+
+// augment matches the unmatched entrant along a shortest alternating path,
+// ending at a bucket for which end returns true.
+func (m *decompositionMatching) augment(entrant int, support func(i, b int) bool, end func(b int) bool) bool {
+	// prev[b] is the entrant that moves into bucket b.
+	prev := make([]int, m.n+1)
+	for b := range prev {
+		prev[b] = -1
+	}
+	members := m.members()
+
+	queue := []int{entrant}
+	seen := map[int]bool{entrant: true}
+	for ; len(queue) > 0; queue = queue[1:] {
+		i := queue[0]
+		for b := range prev {
+			if prev[b] != -1 || !support(i, b) || m.asg[i] == b {
+				continue
+			}
+			prev[b] = i
+			if end(b) {
+				for {
+					x := prev[b]
+					from := m.asg[x]
+					m.asg[x] = b
+					m.count[b]++
+					if from == -1 {
+						return true
+					}
+					m.count[from]--
+					b = from
+				}
+			}
+			for _, x := range members[b] {
+				if !seen[x] {
+					seen[x] = true
+					queue = append(queue, x)
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pull moves an entrant into the bucket along a shortest alternating path
+// starting from a bucket for which start returns true.
+func (m *decompositionMatching) pull(bucket int, support func(i, b int) bool, start func(b int) bool) bool {
+	// next[u] is the bucket into which an entrant moves from u, and mover the
+	// entrant.
+	next := make([]int, m.n+1)
+	mover := make([]int, m.n+1)
+	for b := range next {
+		next[b] = -1
+	}
+	members := m.members()
+
+	seen := make([]bool, m.n+1)
+	seen[bucket] = true
+	for queue := []int{bucket}; len(queue) > 0; queue = queue[1:] {
+		v := queue[0]
+		for u, xs := range members {
+			if seen[u] {
+				continue
+			}
+			for _, x := range xs {
+				if !support(x, v) {
+					continue
+				}
+				seen[u] = true
+				next[u], mover[u] = v, x
+				if start(u) {
+					m.count[u]--
+					m.count[bucket]++
+					for ; u != bucket; u = next[u] {
+						m.asg[mover[u]] = next[u]
+					}
+					return true
+				}
+				queue = append(queue, u)
+				break
+			}
+		}
+	}
+	return false
+}
+
+// members returns the entrants matched to each bucket.
+func (m *decompositionMatching) members() [][]int {
+	members := make([][]int, m.n+1)
+	for i, b := range m.asg {
+		if b != -1 {
+			members[b] = append(members[b], i)
+		}
+	}
+	return members
+}
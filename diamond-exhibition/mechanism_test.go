@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestProbabilisticSerial(t *testing.T) {
+	tests := []struct {
+		name        string
+		available   []uint64
+		preferences [][]int
+		policy      unrankedPolicy
+		want        randomAssignment
+	}{
+		{
+			name:        "identical preferences",
+			available:   []uint64{1, 1},
+			preferences: [][]int{{0, 1}, {0, 1}},
+			want:        randomAssignment{{0.5, 0.5, 0}, {0.5, 0.5, 0}},
+		},
+		{
+			// Bogomolnaia & Moulin (2001), section 2.
+			name:        "ordinally efficient",
+			available:   []uint64{1, 1, 1, 1},
+			preferences: [][]int{{0, 1, 2, 3}, {0, 1, 2, 3}, {1, 0, 3, 2}, {1, 0, 3, 2}},
+			want: randomAssignment{
+				{0.5, 0, 0.5, 0, 0},
+				{0.5, 0, 0.5, 0, 0},
+				{0, 0.5, 0, 0.5, 0},
+				{0, 0.5, 0, 0.5, 0},
+			},
+		},
+		{
+			name:        "capacity",
+			available:   []uint64{2, 1},
+			preferences: [][]int{{0, 1}, {0, 1}, {0, 1}},
+			want: randomAssignment{
+				{2. / 3, 1. / 3, 0},
+				{2. / 3, 1. / 3, 0},
+				{2. / 3, 1. / 3, 0},
+			},
+		},
+		{
+			name:        "insufficient capacity",
+			available:   []uint64{1},
+			preferences: [][]int{{0}, {0}, {0}, {0}},
+			want:        randomAssignment{{0.25, 0.75}, {0.25, 0.75}, {0.25, 0.75}, {0.25, 0.75}},
+		},
+		{
+			name:        "no allocation of unranked",
+			available:   []uint64{1, 1},
+			preferences: [][]int{{0}, {0, 1}},
+			policy:      noAllocation,
+			want:        randomAssignment{{0.5, 0, 0.5}, {0.5, 0.5, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &allocator{
+				available:   tt.available,
+				preferences: tt.preferences,
+				unranked:    tt.policy,
+			}
+			if err := a.init(nil); err != nil {
+				t.Fatalf("%T.init() error %v", a, err)
+			}
+			got := a.probabilisticSerial()
+			if diff := cmp.Diff(tt.want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("probabilisticSerial() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProbabilisticSerialRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	for _, policy := range []unrankedPolicy{requireFullRankings, tiedLast, randomFallback, noAllocation} {
+		for trial := 0; trial < 200; trial++ {
+			a := randomAllocator(rng, policy)
+			if err := a.init(rng); err != nil {
+				t.Fatalf("%T.init() error %v", a, err)
+			}
+			desc := fmt.Sprintf("policy %q; available %v; preferences %v", policy, a.available, a.preferences)
+			p := a.probabilisticSerial()
+
+			// Envy-freeness: every entrant's probability of receiving one of
+			// their top d buckets is at least that of any other entrant.
+			for i := range p {
+				order := a.eatingOrder(i)
+				for j := range p {
+					var pi, pj float64
+					for _, b := range order {
+						pi += p[i][b]
+						pj += p[j][b]
+						if pi < pj-1e-9 {
+							t.Errorf("%s: entrant %d envies %d; p = %v", desc, i, j, p)
+						}
+					}
+				}
+			}
+
+			var lottery []assignment
+			var weights []float64
+			err := a.decompose(p, func(w float64, asg assignment) bool {
+				lottery = append(lottery, asg)
+				weights = append(weights, w)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("%s: decompose(%v) error %v", desc, p, err)
+			}
+
+			n := len(a.available)
+			sum := make(randomAssignment, len(p))
+			for i := range sum {
+				sum[i] = make([]float64, n+1)
+			}
+			var total float64
+			for k, asg := range lottery {
+				total += weights[k]
+				if weights[k] <= 0 {
+					t.Errorf("%s: decompose() weight %v", desc, weights[k])
+				}
+				allocated := make([]uint64, n)
+				for i, b := range asg {
+					if b < 0 {
+						b = n
+					} else {
+						allocated[b]++
+					}
+					sum[i][b] += weights[k]
+				}
+				for b, c := range allocated {
+					if c > a.available[b] {
+						t.Errorf("%s: decompose() assignment %v exceeds capacity of bucket %d", desc, asg, b)
+					}
+				}
+			}
+			if math.Abs(total-1) > 1e-6 {
+				t.Errorf("%s: decompose() weights sum to %v; want 1", desc, total)
+			}
+			if diff := cmp.Diff(p, sum, cmpopts.EquateApprox(0, 1e-6)); diff != "" {
+				t.Errorf("%s: decompose() weighted sum of assignments diff (-want +got):\n%s", desc, diff)
+			}
+		}
+	}
+}
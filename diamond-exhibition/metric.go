@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// A metric scores the outcome of an ordering, for use as the fitness of the
+// genetic search. The outcome is summarised by its profile, profile[c] being
+// the number of entrants incurring cost c, as returned by allocator.choose();
+// i.e. receiving their (c+1)th preference. Greater scores are better and MUST
+// be non-negative.
+type metric interface {
+	score(profile []int) float64
+	// compare returns a negative number, zero, or a positive number as p is
+	// worse than, equal to, or better than q. Unlike score(), which MAY be
+	// rounded, it is exact.
+	compare(p, q []int) int
+	// describe returns a human-readable summary of the profile under the
+	// metric.
+	describe(profile []int) string
+}
+
+// Metrics accepted by the --metric flag.
+const (
+	linearMetric        = "linear"
+	squaredMetric       = "squared"
+	lexicographicMetric = "lexicographic"
+	maximinMetric       = "maximin"
+)
+
+// metricNames are all metrics, in the order in which they are reported.
+var metricNames = []string{linearMetric, squaredMetric, lexicographicMetric, maximinMetric}
+
+// newMetric returns the named metric; the allocator MUST already have been
+// initialised.
+func (a *allocator) newMetric(name string) (metric, error) {
+	switch name {
+	case linearMetric:
+		return a.separable(linearPenalty), nil
+	case squaredMetric:
+		return a.separable(squaredPenalty), nil
+	case lexicographicMetric:
+		return a.lexicographic(), nil
+	case maximinMetric:
+		return maximin{
+			unallocatedCost: a.unallocatedCost,
+			tieBreak:        a.separable(linearPenalty),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown metric %q; must be one of %q", name, metricNames)
+	}
+}
+
+// profile returns the profile of the assignment, as passed to metric.score().
+func (a *allocator) profile(asg assignment) []int {
+	cs := a.costs()
+	p := make([]int, a.unallocatedCost+1)
+	for i, b := range asg {
+		if b < 0 {
+			p[a.unallocatedCost]++
+		} else {
+			p[cs[i][b]]++
+		}
+	}
+	return p
+}
+
+// A penalty maps the cost incurred by an entrant to the points deducted from
+// a separable metric.
+type penalty struct {
+	name string
+	of   func(cost int) int
+}
+
+var (
+	// linearPenalty deducts one point per step down in preference; i.e.
+	// constant marginal utility.
+	linearPenalty = penalty{"rank", func(c int) int { return c }}
+	// squaredPenalty is convex, deducting more for each successive step down
+	// in preference and therefore favouring the worst-off entrants.
+	squaredPenalty = penalty{"squared rank", func(c int) int { return c * c }}
+)
+
+// A separable metric is the sum of a penalty incurred by each entrant,
+// deducted from the sum of the greatest penalties that they could incur. As a
+// sum over entrants, it can also be optimised exactly by allocator.optimal().
+type separable struct {
+	penalty
+	max int
+}
+
+func (a *allocator) separable(p penalty) separable {
+	s := separable{penalty: p}
+	for _, w := range a.worst {
+		s.max += p.of(w)
+	}
+	return s
+}
+
+func (s separable) total(profile []int) int {
+	var t int
+	for c, n := range profile {
+		t += n * s.of(c)
+	}
+	return t
+}
+
+func (s separable) score(profile []int) float64 {
+	return float64(s.max - s.total(profile))
+}
+
+func (s separable) compare(p, q []int) int {
+	return s.total(q) - s.total(p)
+}
+
+func (s separable) describe(profile []int) string {
+	return fmt.Sprintf("%0.f/%d (sum of %s %d)", s.score(profile), s.max, s.name, s.total(profile))
+}
+
+// lexicographic is the rank-maximal metric, first maximising the number of
+// entrants receiving their first preference, then their second, and so on.
+// It is computed as the sum of weights (k+1)^-c for each entrant incurring
+// cost c, so that a single additional entrant at a better rank outweighs all
+// entrants at worse ones.
+//
+// The genetic-algorithm library requires a float64 score, which is only exact
+// if the weights span no more than its 53-bit significand, i.e. if
+// (k+1)^(n+1) <= 2^53; this isn't the case for the Diamond Exhibition. Scores
+// otherwise only guide the library's selection, with improvements at lower
+// ranks lost to rounding, while the search compares profiles exactly. Scores
+// are kept below k+1, instead of being integers, as the library misbehaves
+// when adding one to a score has no effect.
+type lexicographic struct {
+	weights []float64
+}
+
+func (a *allocator) lexicographic() lexicographic {
+	base := float64(len(a.preferences) + 1)
+	l := lexicographic{weights: make([]float64, a.unallocatedCost+1)}
+	for c := range l.weights {
+		l.weights[c] = math.Pow(base, -float64(c))
+	}
+	return l
+}
+
+func (l lexicographic) score(profile []int) float64 {
+	var s float64
+	for c, n := range profile {
+		s += float64(n) * l.weights[c]
+	}
+	return s
+}
+
+// compare compares the number of entrants receiving each preference, from the
+// first, as all profiles have the same total.
+func (l lexicographic) compare(p, q []int) int {
+	for c := range p {
+		if d := p[c] - q[c]; d != 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (l lexicographic) describe(profile []int) string {
+	none := len(profile) - 1
+	// Preferences that nobody received, beyond the worst that anybody did,
+	// are omitted.
+	last := 0
+	for c, n := range profile[:none] {
+		if n > 0 {
+			last = c
+		}
+	}
+	var counts []string
+	for c, n := range profile[:last+1] {
+		counts = append(counts, fmt.Sprintf("#%d:%d", c+1, n))
+	}
+	counts = append(counts, fmt.Sprintf("none:%d", profile[none]))
+	return fmt.Sprintf("entrants per preference [%s]", strings.Join(counts, " "))
+}
+
+// maximin maximises the preference received by the worst-off entrant,
+// breaking ties with the linear metric.
+type maximin struct {
+	unallocatedCost int
+	tieBreak        separable
+}
+
+func (m maximin) worst(profile []int) int {
+	for c := len(profile) - 1; c > 0; c-- {
+		if profile[c] > 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (m maximin) score(profile []int) float64 {
+	return float64(m.unallocatedCost-m.worst(profile))*float64(m.tieBreak.max+1) + m.tieBreak.score(profile)
+}
+
+func (m maximin) compare(p, q []int) int {
+	if d := m.worst(q) - m.worst(p); d != 0 {
+		return d
+	}
+	return m.tieBreak.compare(p, q)
+}
+
+func (m maximin) describe(profile []int) string {
+	w := m.worst(profile)
+	if w == m.unallocatedCost {
+		return "worst-off entrant receives nothing"
+	}
+	return fmt.Sprintf("worst-off entrant receives preference #%d", w+1)
+}
+
+// describeAll returns a summary of the assignment under every metric.
+func (a *allocator) describeAll(asg assignment) string {
+	p := a.profile(asg)
+	var lines []string
+	for _, name := range metricNames {
+		m, err := a.newMetric(name)
+		if err != nil {
+			// Implies a bug as all names are known.
+			panic(err)
+		}
+		lines = append(lines, fmt.Sprintf("%-13s %s", name, m.describe(p)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	// Every entrant receives a bucket, so at worst their third preference.
+	a := &allocator{
+		available:   []uint64{1, 1, 1},
+		preferences: [][]int{{0, 1, 2}, {0, 1, 2}, {0, 1, 2}},
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+
+	tests := []struct {
+		metric string
+		// better MUST score strictly greater than worse.
+		better, worse []int
+	}{
+		{
+			metric: linearMetric,
+			better: []int{1, 1, 1, 0},
+			worse:  []int{1, 0, 2, 0},
+		},
+		{
+			metric: squaredMetric,
+			// Equal under the linear metric.
+			better: []int{0, 3, 0, 0},
+			worse:  []int{1, 1, 1, 0},
+		},
+		{
+			metric: lexicographicMetric,
+			// One more first choice outweighs the worst-off entrant.
+			better: []int{2, 0, 1, 0},
+			worse:  []int{1, 2, 0, 0},
+		},
+		{
+			metric: maximinMetric,
+			// A better worst-off entrant outweighs the sum of ranks.
+			better: []int{0, 3, 0, 0},
+			worse:  []int{2, 0, 1, 0},
+		},
+		{
+			metric: maximinMetric,
+			// Ties are broken by the sum of ranks.
+			better: []int{1, 1, 1, 0},
+			worse:  []int{0, 2, 1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		m, err := a.newMetric(tt.metric)
+		if err != nil {
+			t.Fatalf("%T.newMetric(%q) error %v", a, tt.metric, err)
+		}
+		better, worse := m.score(tt.better), m.score(tt.worse)
+		if better <= worse {
+			t.Errorf("%s metric: score(%v) = %v <= score(%v) = %v", tt.metric, tt.better, better, tt.worse, worse)
+		}
+		if got := m.compare(tt.better, tt.worse); got <= 0 {
+			t.Errorf("%s metric: compare(%v, %v) = %d; want > 0", tt.metric, tt.better, tt.worse, got)
+		}
+		if got := m.compare(tt.worse, tt.better); got >= 0 {
+			t.Errorf("%s metric: compare(%v, %v) = %d; want < 0", tt.metric, tt.worse, tt.better, got)
+		}
+		if got := m.compare(tt.better, tt.better); got != 0 {
+			t.Errorf("%s metric: compare(%v, itself) = %d; want 0", tt.metric, tt.better, got)
+		}
+		if worst := m.score([]int{0, 0, 3, 0}); worst < 0 {
+			t.Errorf("%s metric: score(all third preference) = %v; MUST be non-negative", tt.metric, worst)
+		}
+	}
+
+	if _, err := a.newMetric("unknown"); err == nil {
+		t.Errorf(`%T.newMetric("unknown") got nil error`, a)
+	}
+}
+
+func TestLexicographicCompare(t *testing.T) {
+	// With 3 entrants and 40 buckets, weights range from 1 down to
+	// 4^-40 = 2^-80, so scores can't distinguish the lowest preferences.
+	const buckets = 40
+	a := &allocator{
+		available:   make([]uint64, buckets),
+		preferences: make([][]int, 3),
+	}
+	for b := range a.available {
+		a.available[b] = 1
+	}
+	for i := range a.preferences {
+		for b := 0; b < buckets; b++ {
+			a.preferences[i] = append(a.preferences[i], b)
+		}
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+
+	// The least significant improvement, one entrant moving from being
+	// unallocated to their last preference.
+	m := a.lexicographic()
+	better := make([]int, buckets+1)
+	better[0], better[buckets-1] = 2, 1
+	worse := make([]int, buckets+1)
+	worse[0], worse[buckets] = 2, 1
+	if b, w := m.score(better), m.score(worse); b != w {
+		t.Fatalf("score(%v) = %v != score(%v) = %v; test requires rounding", better, b, worse, w)
+	}
+	if got := m.compare(better, worse); got <= 0 {
+		t.Errorf("compare(%v, %v) = %d; want > 0", better, worse, got)
+	}
+	if got := m.compare(worse, better); got >= 0 {
+		t.Errorf("compare(%v, %v) = %d; want < 0", worse, better, got)
+	}
+}
+
+func TestSimulateUsesMetric(t *testing.T) {
+	a := &allocator{
+		available:   []uint64{1, 1, 1},
+		preferences: [][]int{{0, 1, 2}, {0, 2, 1}, {0, 1, 2}},
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+	// Entrants receive their first, second, and second preferences.
+	o := a.newOrdering()
+
+	for _, tt := range []struct {
+		metric string
+		want   float64
+	}{
+		{linearMetric, 6 - (0 + 1 + 1)},
+		{squaredMetric, 12 - (0 + 1 + 1)},
+		{maximinMetric, float64(3-1)*(6+1) + 4},
+	} {
+		m, err := a.newMetric(tt.metric)
+		if err != nil {
+			t.Fatalf("%T.newMetric(%q) error %v", a, tt.metric, err)
+		}
+		a.metric = m
+		if got := o.Simulate(context.Background()); got != tt.want {
+			t.Errorf("%s metric: Simulate() = %v; want %v", tt.metric, got, tt.want)
+		}
+		if got, want := a.fitness(o.assignment()), tt.want; got != want {
+			t.Errorf("%s metric: fitness(assignment()) = %v; want %v", tt.metric, got, want)
+		}
+	}
+}
+
+func TestLexicographicDescribe(t *testing.T) {
+	a := &allocator{
+		available:   []uint64{1, 1, 1},
+		preferences: [][]int{{0, 1, 2}, {0, 1, 2}, {0, 1, 2}},
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+	l := a.lexicographic()
+
+	for _, tt := range []struct {
+		profile []int
+		want    string
+	}{
+		{[]int{1, 2, 0, 0}, "entrants per preference [#1:1 #2:2 none:0]"},
+		{[]int{1, 0, 1, 1}, "entrants per preference [#1:1 #2:0 #3:1 none:1]"},
+		{[]int{0, 0, 0, 3}, "entrants per preference [#1:0 none:3]"},
+	} {
+		if got := l.describe(tt.profile); got != tt.want {
+			t.Errorf("describe(%v) got %q; want %q", tt.profile, got, tt.want)
+		}
+	}
+}
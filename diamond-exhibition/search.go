@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// params are the parameters used by the genetic algorithm. Instead of locking
+// in a single set of parameters, we perform a sweep of a range.
+type params struct {
+	mutRate  float64
+	polygamy int
+}
+
+// sweep returns every combination of params, in the order in which they are
+// tried.
+func sweep() []params {
+	var ps []params
+	for mutRate := 1.; mutRate > 0.001; mutRate /= 1.5 {
+		for polygamy := 0; polygamy <= 3; polygamy++ {
+			ps = append(ps, params{mutRate, polygamy})
+		}
+	}
+	return ps
+}
+
+// A searchConfig configures the genetic search.
+type searchConfig struct {
+	// A number of "islands", each with a "population" are bred for a set
+	// number of "generations", after which the best performing orderings of
+	// the islands "migrate". If this fails to produce an improved fitness
+	// after a threshold number of rounds, it is considered stable and no
+	// further optimisation is performed.
+	islands, perIsland, generations, stableThreshold int
+
+	// sequential evolves one set of params at a time, each starting from the
+	// individuals of the last improvement, as for the historical allocation.
+	// Otherwise all params of a sweep start from the individuals at the
+	// beginning of the sweep and are evolved concurrently by the number of
+	// workers, which has no effect on the result.
+	sequential bool
+	workers    int
+	// maxSweeps, if non-zero, stops the search after that many sweeps, even
+	// if it is still improving.
+	maxSweeps int
+
+	// checkpoint is the path to which the search is saved, from which it is
+	// loaded if resume is true.
+	checkpoint string
+	resume     bool
+}
+
+// defaultSearchConfig has the sizes used for the historical allocation.
+var defaultSearchConfig = searchConfig{
+	islands:         20,
+	perIsland:       10,
+	generations:     50,
+	stableThreshold: 15,
+}
+
+// A search is the state of the genetic search for the fittest ordering.
+type search struct {
+	*allocator
+	cfg searchConfig
+	// newSrc returns the source for evolving the params at index i of
+	// sweep(), during the given sweep.
+	newSrc func(sweep, i int) rand.Source
+
+	best     float64
+	champion *ordering
+	// bestProfile is the profile of the champion, by which improvements are
+	// judged as the best fitness MAY be rounded; see metric.compare().
+	bestProfile []int
+	// individuals are the full set of orderings from previous optimisations,
+	// fed into the next run so we don't start from a blank slate. This
+	// maintains a diverse range of high-performing orderings whereas only
+	// feeding in the best would favour local minima.
+	individuals []*ordering
+
+	// sweeps is the number of completed sweeps, and before the best profile
+	// at the start of the current one; meaningless until the first sweep has
+	// started.
+	sweeps int
+	before []int
+	// Progress through the current sweep: next is the index of the next
+	// params under sequential search, and evolved the evolutions, keyed by
+	// index, completed by concurrent search but not yet recorded.
+	next    int
+	evolved map[int]evolution
+
+	// checkpoint, if non-nil, saves the search after each evolution.
+	checkpoint *checkpointer
+	// onImprove, if non-nil, is called whenever best is improved upon.
+	onImprove func(params)
+	// printErrs prints errors in full instead of a progress indicator.
+	printErrs bool
+}
+
+// midSweep reports whether the search was resumed part way through a sweep.
+func (s *search) midSweep() bool {
+	return s.next > 0 || len(s.evolved) > 0
+}
+
+// run performs sweeps until a full sweep is unable to improve on the best
+// fitness, in which case it reports that the search converged. The first sweep
+// is always performed, regardless of the starting fitness. The search
+// otherwise stops early, with the best ordering found so far, once ctx is
+// cancelled or after cfg.maxSweeps. The last checkpoint remains consistent, so
+// the search can be resumed.
+func (s *search) run(ctx context.Context) (converged bool, _ error) {
+	for s.midSweep() || s.sweeps == 0 || s.metric.compare(s.bestProfile, s.before) > 0 {
+		if !s.midSweep() {
+			if max := s.cfg.maxSweeps; max > 0 && s.sweeps >= max {
+				return false, nil
+			}
+			s.before = s.bestProfile
+			stderr("|") // progress indicator for a new sweep
+		}
+
+		var err error
+		if s.cfg.sequential {
+			err = s.sweepSequential(ctx)
+		} else {
+			err = s.sweepConcurrent(ctx)
+		}
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		s.sweeps++
+		s.next, s.evolved = 0, nil
+		if err := s.save(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// improves reports whether p is better than the best profile.
+func (s *search) improves(p []int) bool {
+	return s.metric.compare(p, s.bestProfile) > 0
+}
+
+// interrupted reports whether the evolution was cut short by cancellation of
+// ctx, in which case it MUST NOT be recorded.
+func interrupted(ctx context.Context, ev evolution) bool {
+	return ev.err != nil && ctx.Err() != nil
+}
+
+// save writes a checkpoint, if configured.
+func (s *search) save() error {
+	if s.checkpoint == nil {
+		return nil
+	}
+	return s.checkpoint.save(s)
+}
+
+// An evolution is the result of evolving a single set of params.
+type evolution struct {
+	fitness     float64
+	profile     []int // of the champion
+	champion    *ordering
+	individuals []*ordering
+	err         error
+}
+
+// evolve breeds islands from the existing individuals, which it modifies,
+// until the champion fitness is stable.
+func (s *search) evolve(ctx context.Context, p params, src rand.Source, existing []*ordering) (ev evolution) {
+	defer func() {
+		// The mu8 package uses panics in some places instead of bubbling up
+		// errors. To simplify reporting, we do the same.
+		if r := recover(); r != nil {
+			ev = evolution{err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	c := s.cfg
+	islands := s.islands(c.islands, c.islands*c.perIsland, src, existing...)
+	var (
+		last   float64
+		stable int
+	)
+	for stable < c.stableThreshold {
+		if err := islands.Advance(ctx, p.mutRate, p.polygamy, c.generations, c.islands); err != nil {
+			panic(err)
+		}
+		islands.Crossover() // inter-island migration
+
+		if fit := islands.ChampionFitness(); fit == last {
+			stable++
+		} else {
+			stable = 0
+			last = fit
+		}
+	}
+
+	ev = evolution{
+		fitness:  islands.ChampionFitness(),
+		champion: islands.Champion(),
+	}
+	for _, p := range islands.Populations() {
+		ev.individuals = append(ev.individuals, p.Individuals()...)
+	}
+
+	// The library chooses arbitrarily between individuals with the same
+	// fitness, which MAY differ in their profiles if the fitness is rounded.
+	ev.profile = s.profile(ev.champion.assignment())
+	for _, ind := range ev.individuals {
+		if ind.Simulate(ctx) != ev.fitness {
+			continue
+		}
+		if p := s.profile(ind.assignment()); s.metric.compare(p, ev.profile) > 0 {
+			ev.champion, ev.profile = ind, p
+		}
+	}
+	return ev
+}
+
+// record updates the search with the evolution if it improves on the best
+// fitness, printing a progress indicator.
+func (s *search) record(p params, ev evolution) {
+	switch {
+	case ev.err != nil:
+		if s.printErrs {
+			stderrLn("\n%v", ev.err)
+		} else {
+			// Some parameters and starting conditions result in non-fatal
+			// errors, which provide little information when occurring only
+			// sporadically. If too many # appear, run again with the
+			// --print_errs flag.
+			stderr("#") // progress indicator for a failed optimisation
+		}
+	case s.improves(ev.profile):
+		s.best, s.bestProfile = ev.fitness, ev.profile
+		s.champion = ev.champion
+		s.individuals = ev.individuals
+		if s.onImprove != nil {
+			s.onImprove(p)
+		}
+	default:
+		stderr(".") // progress indicator for a successful but weaker optimisation
+	}
+}
+
+func (s *search) sweepSequential(ctx context.Context) error {
+	ps := sweep()
+	for s.next < len(ps) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := ps[s.next]
+		ev := s.evolve(ctx, p, s.newSrc(s.sweeps, s.next), s.individuals)
+		if interrupted(ctx, ev) {
+			return ctx.Err()
+		}
+		s.record(p, ev)
+		s.next++
+
+		// The final checkpoint of the sweep is saved by run(). As the
+		// individuals are modified in place, even by unsuccessful evolutions,
+		// checkpoints are only consistent between them.
+		if s.next < len(ps) {
+			if err := s.save(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sweepConcurrent evolves every set of params concurrently, recording the
+// results in sweep() order. The result is therefore equivalent to evolving
+// them sequentially, each from the same starting individuals.
+func (s *search) sweepConcurrent(ctx context.Context) error {
+	ps := sweep()
+	if s.evolved == nil {
+		s.evolved = make(map[int]evolution)
+	}
+
+	// Only the first evolution with the greatest fitness can be recorded as an
+	// improvement, so the orderings of all others are discarded as soon as
+	// they complete, to save memory.
+	leader := -1
+	for i, ev := range s.evolved {
+		if ev.champion != nil {
+			leader = i
+		}
+	}
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	add := func(i int, ev evolution) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case ev.err != nil || !s.improves(ev.profile):
+			ev.retire()
+		case leader == -1:
+			leader = i
+		default:
+			l := s.evolved[leader]
+			if c := s.metric.compare(ev.profile, l.profile); c > 0 || (c == 0 && i < leader) {
+				l.retire()
+				s.evolved[leader] = l
+				leader = i
+			} else {
+				ev.retire()
+			}
+		}
+		s.evolved[i] = ev
+
+		if err := s.save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var pending []int
+	for i := range ps {
+		if _, ok := s.evolved[i]; !ok {
+			pending = append(pending, i)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.cfg.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if ev := s.evolve(ctx, ps[i], s.newSrc(s.sweeps, i), s.cloneIndividuals()); !interrupted(ctx, ev) {
+					add(i, ev)
+				}
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	// Improvements before the leader are still reported as progress, but are
+	// then superseded by it.
+	for i, p := range ps {
+		s.record(p, s.evolved[i])
+	}
+	return nil
+}
+
+// retire discards the orderings of an evolution that can't be recorded as an
+// improvement.
+func (ev *evolution) retire() {
+	ev.champion = nil
+	ev.individuals = nil
+}
+
+// cloneIndividuals returns a deep copy of the search's individuals, allowing
+// them to be evolved concurrently.
+func (s *search) cloneIndividuals() []*ordering {
+	clones := make([]*ordering, len(s.individuals))
+	for i, ind := range s.individuals {
+		clones[i] = s.newOrdering()
+		clones[i].CloneFrom(ind)
+	}
+	return clones
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/seed"
+)
+
+// testSearch returns a search over random rankings, small enough to run
+// quickly.
+func testSearch(t *testing.T, cfg searchConfig) *search {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(42))
+	a := &allocator{
+		available:   []uint64{12, 10, 8, 6, 4},
+		preferences: make([][]int, 40),
+	}
+	// Similar preferences maximise contention, and therefore the scope for
+	// optimisation.
+	for i := range a.preferences {
+		prefs := []int{0, 1, 2, 3, 4}
+		for j := 0; j < 3; j++ {
+			k := rng.Intn(len(prefs) - 1)
+			prefs[k], prefs[k+1] = prefs[k+1], prefs[k]
+		}
+		a.preferences[i] = prefs
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+
+	var key [32]byte
+	key[0] = 1
+	start := a.newOrderings(1, seed.Default.NewSource(key))[0]
+	return &search{
+		allocator:   a,
+		cfg:         cfg,
+		best:        start.Simulate(context.Background()),
+		champion:    start,
+		bestProfile: a.profile(start.assignment()),
+		newSrc: func(sweep, i int) rand.Source {
+			return seed.Default.NewSource(entropy.Derive(key, fmt.Sprintf("search/%d/%d", sweep, i)))
+		},
+	}
+}
+
+var testSearchConfig = searchConfig{
+	islands:         2,
+	perIsland:       10,
+	generations:     5,
+	stableThreshold: 3,
+}
+
+func TestConcurrentSearchDeterministic(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	type result struct {
+		Best   float64
+		Order  []int
+		Sweeps int
+	}
+	var want *result
+
+	for _, procs := range []int{1, 4} {
+		for _, workers := range []int{1, 3, 16} {
+			runtime.GOMAXPROCS(procs)
+			cfg := testSearchConfig
+			cfg.workers = workers
+			s := testSearch(t, cfg)
+			if converged, err := s.run(context.Background()); err != nil || !converged {
+				t.Fatalf("GOMAXPROCS=%d, %d workers: %T.run() got (converged = %t, err = %v); want (true, nil)", procs, workers, s, converged, err)
+			}
+
+			if s.sweeps < 2 {
+				t.Fatalf("GOMAXPROCS=%d, %d workers: search made %d sweeps; test requires an improvement", procs, workers, s.sweeps)
+			}
+			got := &result{s.best, s.champion.order, s.sweeps}
+			if want == nil {
+				want = got
+				continue
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("GOMAXPROCS=%d, %d workers: search result diff (-first +got):\n%s", procs, workers, diff)
+			}
+		}
+	}
+}
+
+func TestSearchStopsEarly(t *testing.T) {
+	for _, sequential := range []bool{true, false} {
+		cfg := testSearchConfig
+		cfg.sequential = sequential
+		cfg.workers = 3
+
+		full := testSearch(t, cfg)
+		if _, err := full.run(context.Background()); err != nil {
+			t.Fatalf("sequential = %t: uninterrupted %T.run() error %v", sequential, full, err)
+		}
+
+		t.Run(fmt.Sprintf("sequential=%t/max sweeps", sequential), func(t *testing.T) {
+			cfg := cfg
+			cfg.maxSweeps = 1
+			s := testSearch(t, cfg)
+			converged, err := s.run(context.Background())
+			if err != nil || converged {
+				t.Fatalf("%T.run() got (converged = %t, err = %v); want (false, nil)", s, converged, err)
+			}
+			if s.sweeps != 1 {
+				t.Errorf("%T.run() with maxSweeps = 1 made %d sweeps", s, s.sweeps)
+			}
+			if s.best > full.best {
+				t.Errorf("%T.run() with maxSweeps = 1 got best %v; greater than uninterrupted %v", s, s.best, full.best)
+			}
+		})
+
+		t.Run(fmt.Sprintf("sequential=%t/cancelled", sequential), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Cancellation part way through the first sweep, after an
+			// improvement, must keep the best ordering found so far.
+			s := testSearch(t, cfg)
+			start := s.best
+			newSrc := s.newSrc
+			var calls atomic.Int32
+			s.newSrc = func(sweep, i int) rand.Source {
+				if calls.Add(1) == 5 {
+					cancel()
+				}
+				return newSrc(sweep, i)
+			}
+			converged, err := s.run(ctx)
+			if err != nil || converged {
+				t.Fatalf("%T.run() got (converged = %t, err = %v); want (false, nil)", s, converged, err)
+			}
+			if s.sweeps != 0 {
+				t.Errorf("%T.run() cancelled during first sweep; completed %d sweeps", s, s.sweeps)
+			}
+			if got := s.champion.Simulate(ctx); got != s.best {
+				t.Errorf("%T.run() cancelled; champion fitness %v != best %v", s, got, s.best)
+			}
+			if s.best < start {
+				t.Errorf("%T.run() cancelled; best %v worse than start %v", s, s.best, start)
+			}
+		})
+	}
+}
+
+func TestSearchRunsFirstSweep(t *testing.T) {
+	for _, sequential := range []bool{true, false} {
+		cfg := testSearchConfig
+		cfg.sequential = sequential
+		cfg.workers = 3
+
+		// Scores MAY be zero, e.g. if every entrant receives their worst
+		// preference, which MUST NOT be mistaken for a sweep that didn't
+		// improve.
+		s := testSearch(t, cfg)
+		s.best = 0
+		converged, err := s.run(context.Background())
+		if err != nil || !converged {
+			t.Fatalf("sequential = %t: %T.run() got (converged = %t, err = %v); want (true, nil)", sequential, s, converged, err)
+		}
+		if s.sweeps == 0 {
+			t.Errorf("sequential = %t: %T.run() from best = 0 made no sweeps", sequential, s)
+		}
+		if s.best <= 0 {
+			t.Errorf("sequential = %t: %T.run() from best = 0 got best %v; want improvement", sequential, s, s.best)
+		}
+	}
+}
+
+func TestSearchComparesProfiles(t *testing.T) {
+	// Entrants 0 and 1 both prefer bucket 0, after which their next
+	// available preferences are bucket 1 at #36 and bucket 2 at #35
+	// respectively, behind buckets without capacity. Entrant 2 receives
+	// bucket 3 regardless.
+	const buckets = 40
+	a := &allocator{
+		available:   make([]uint64, buckets),
+		preferences: [][]int{{0}, {0}, {3, 0, 1, 2}},
+	}
+	for _, b := range []int{0, 1, 2, 3, 38, 39} {
+		a.available[b] = 1
+	}
+	for b := 4; b < 37; b++ {
+		a.preferences[0] = append(a.preferences[0], b)
+		a.preferences[1] = append(a.preferences[1], b)
+	}
+	a.preferences[0] = append(a.preferences[0], 37, 1, 2, 3, 38, 39)
+	a.preferences[1] = append(a.preferences[1], 2, 37, 1, 3, 38, 39)
+	for b := 4; b < buckets; b++ {
+		a.preferences[2] = append(a.preferences[2], b)
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+	a.metric = a.lexicographic()
+
+	// Entrant 1 choosing first is worse, but scores the same as the
+	// difference is lost to rounding.
+	worse, err := a.orderingOf([]int{1, 0, 2})
+	if err != nil {
+		t.Fatalf("%T.orderingOf() error %v", a, err)
+	}
+	better, err := a.orderingOf([]int{0, 1, 2})
+	if err != nil {
+		t.Fatalf("%T.orderingOf() error %v", a, err)
+	}
+	ctx := context.Background()
+	if b, w := better.Simulate(ctx), worse.Simulate(ctx); b != w {
+		t.Fatalf("Simulate() of orderings = %v and %v; test requires rounding", b, w)
+	}
+
+	for _, sequential := range []bool{true, false} {
+		cfg := testSearchConfig
+		cfg.sequential = sequential
+		cfg.workers = 3
+
+		var key [32]byte
+		s := &search{
+			allocator:   a,
+			cfg:         cfg,
+			best:        worse.Simulate(ctx),
+			champion:    worse,
+			bestProfile: a.profile(worse.assignment()),
+			newSrc: func(sweep, i int) rand.Source {
+				return seed.Default.NewSource(entropy.Derive(key, fmt.Sprintf("search/%d/%d", sweep, i)))
+			},
+		}
+		if converged, err := s.run(ctx); err != nil || !converged {
+			t.Fatalf("sequential = %t: %T.run() got (converged = %t, err = %v); want (true, nil)", sequential, s, converged, err)
+		}
+		if diff := cmp.Diff(better.assignment(), s.champion.assignment()); diff != "" {
+			t.Errorf("sequential = %t: %T.run() champion assignment diff (-want +got):\n%s", sequential, s, diff)
+		}
+	}
+}
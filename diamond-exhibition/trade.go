@@ -0,0 +1,183 @@
+package main
+
+import "sort"
+
+// A move reassigns an entrant from one bucket to another, with -1 denoting no
+// bucket.
+type move struct {
+	entrant, from, to int
+}
+
+// A trade is a set of moves, executed together, that leaves no entrant worse
+// off and at least one strictly better off, with respect to their rankings.
+// It is either a cycle, in which each entrant takes the place vacated by
+// another, or a chain ending at a bucket with spare capacity.
+type trade []move
+
+// tradeGraph describes, for an assignment, which entrants are willing to move
+// between which buckets. Nodes are buckets, plus a virtual bucket for entrants
+// that receive nothing, as in optimal(); nobody is willing to move to it.
+type tradeGraph struct {
+	none int
+	// willing[u][v] is an entrant assigned to u who considers v at least as
+	// good, or -1 if there is none.
+	willing [][]int
+	spare   []bool
+}
+
+func (a *allocator) tradeGraph(asg assignment, cs [][]int) *tradeGraph {
+	n := len(a.available)
+	g := &tradeGraph{
+		none:    n,
+		willing: make([][]int, n+1),
+		spare:   make([]bool, n+1),
+	}
+	for u := range g.willing {
+		g.willing[u] = make([]int, n+1)
+		for v := range g.willing[u] {
+			g.willing[u][v] = -1
+		}
+	}
+
+	allocated := make([]uint64, n)
+	for x, u := range asg {
+		if u >= 0 {
+			allocated[u]++
+		}
+		cu := a.cost(cs, x, u)
+		if u < 0 {
+			u = g.none
+		}
+		for v, cv := range cs[x] {
+			if v != u && cv >= 0 && cv <= cu && g.willing[u][v] == -1 {
+				g.willing[u][v] = x
+			}
+		}
+	}
+	for b, n := range allocated {
+		g.spare[b] = n < a.available[b]
+	}
+	return g
+}
+
+// cost returns the cost to the entrant of the bucket, which MAY be -1 to
+// denote no bucket.
+func (a *allocator) cost(cs [][]int, entrant, bucket int) int {
+	if bucket < 0 {
+		return a.unallocatedCost
+	}
+	return cs[entrant][bucket]
+}
+
+// improvement returns a trade that strictly improves the allocation of the
+// first possible entrant, to the best bucket that can be freed for them, or
+// nil if the assignment is Pareto efficient.
+//
+// An entrant x, assigned to u, strictly prefers v. This is a Pareto
+// improvement if and only if there is a path from v, along which each entrant
+// is willing to move to the next bucket, to either u or a bucket with spare
+// capacity. Searching from every such (x, v) is therefore exhaustive.
+func (a *allocator) improvement(asg assignment, cs [][]int) trade {
+	g := a.tradeGraph(asg, cs)
+
+	for x, u := range asg {
+		cu := a.cost(cs, x, u)
+		if u < 0 {
+			u = g.none
+		}
+
+		var better []int
+		for v, cv := range cs[x] {
+			if cv >= 0 && cv < cu {
+				better = append(better, v)
+			}
+		}
+		sort.SliceStable(better, func(i, j int) bool {
+			return cs[x][better[i]] < cs[x][better[j]]
+		})
+
+		for _, v := range better {
+			if path := g.path(v, u); path != nil {
+				t := trade{{entrant: x, from: asg[x], to: v}}
+				for i := 0; i+1 < len(path); i++ {
+					t = append(t, move{
+						entrant: g.willing[path[i]][path[i+1]],
+						from:    path[i],
+						to:      path[i+1],
+					})
+				}
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// path returns the shortest path of buckets from src to either dst or a bucket
+// with spare capacity, such that there is an entrant willing to make each
+// step, or nil if there is no such path. Paths never continue beyond dst,
+// the bucket vacated by the entrant initiating the trade.
+func (g *tradeGraph) path(src, dst int) []int {
+	prev := make([]int, len(g.willing))
+	for v := range prev {
+		prev[v] = -1
+	}
+	seen := make([]bool, len(g.willing))
+	seen[src] = true
+
+	for queue := []int{src}; len(queue) > 0; queue = queue[1:] {
+		u := queue[0]
+		if u == dst || g.spare[u] {
+			var path []int
+			for v := u; v != -1; v = prev[v] {
+				path = append([]int{v}, path...)
+			}
+			return path
+		}
+		for v, x := range g.willing[u] {
+			if x != -1 && !seen[v] {
+				seen[v] = true
+				prev[v] = u
+				queue = append(queue, v)
+			}
+		}
+	}
+	return nil
+}
+
+// paretoEfficient reports whether no entrant can be made better off without
+// making another worse off, with respect to their rankings. Under the
+// tiedLast and randomFallback policies, entrants are indifferent between all
+// unranked buckets, regardless of the order in which they are tried.
+func (a *allocator) paretoEfficient(asg assignment) bool {
+	return a.improvement(asg, a.costs()) == nil
+}
+
+// tradeCycles returns a Pareto-efficient assignment, with every entrant at
+// least as well off as under asg, along with the trades made to achieve it.
+// It is a top-trading-cycles pass: the first entrant who can be made better
+// off trades for the most preferred bucket that can be freed for them, and
+// this repeats until no such entrant remains. Each trade strictly reduces the
+// total cost, so the pass terminates.
+//
+// The assignment of any ordering is already Pareto efficient when every
+// entrant ranks every bucket, as Simulate() is then a serial dictatorship, so
+// no trades are made. With partial rankings, an entrant receiving an unranked
+// bucket may hold one that another ranked, while being indifferent to the
+// alternatives.
+func (a *allocator) tradeCycles(asg assignment) (assignment, []trade) {
+	asg = append(assignment{}, asg...)
+	cs := a.costs()
+
+	var trades []trade
+	for {
+		t := a.improvement(asg, cs)
+		if t == nil {
+			return asg, trades
+		}
+		for _, m := range t {
+			asg[m.entrant] = m.to
+		}
+		trades = append(trades, t)
+	}
+}
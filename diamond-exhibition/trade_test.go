@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// bruteForceParetoEfficient reports whether no assignment, respecting bucket
+// capacities, leaves every entrant at least as well off as asg and one
+// strictly better off.
+func bruteForceParetoEfficient(a *allocator, asg assignment) bool {
+	cs := a.costs()
+	alt := make(assignment, len(asg))
+	allocated := make([]uint64, len(a.available))
+
+	var recurse func(i int, strict bool) bool // returns true if improved
+	recurse = func(i int, strict bool) bool {
+		if i == len(alt) {
+			return strict
+		}
+		current := a.cost(cs, i, asg[i])
+		if current == a.unallocatedCost {
+			alt[i] = -1
+			if recurse(i+1, strict) {
+				return true
+			}
+		}
+		for b, c := range cs[i] {
+			if c < 0 || c > current || allocated[b] == a.available[b] {
+				continue
+			}
+			allocated[b]++
+			alt[i] = b
+			improved := recurse(i+1, strict || c < current)
+			allocated[b]--
+			if improved {
+				return true
+			}
+		}
+		return false
+	}
+	return !recurse(0, false)
+}
+
+func TestTradeCycles(t *testing.T) {
+	a := &allocator{
+		available:   []uint64{1, 1, 1},
+		preferences: [][]int{{0}, {0}, {1, 2}},
+		unranked:    tiedLast,
+	}
+	if err := a.init(nil); err != nil {
+		t.Fatalf("%T.init() error %v", a, err)
+	}
+	// Entrant 1 falls back on bucket 1, which entrant 2 ranked first, leaving
+	// entrant 2 with their second preference even though entrant 1 is
+	// indifferent.
+	asg := assignment{0, 1, 2}
+	if a.paretoEfficient(asg) {
+		t.Fatalf("paretoEfficient(%v) got true; want false", asg)
+	}
+
+	got, trades := a.tradeCycles(asg)
+	if want := (assignment{0, 2, 1}); !cmp.Equal(want, got) {
+		t.Errorf("tradeCycles(%v) got assignment %v; want %v", asg, got, want)
+	}
+	wantTrades := []trade{{
+		{entrant: 2, from: 2, to: 1},
+		{entrant: 1, from: 1, to: 2},
+	}}
+	if diff := cmp.Diff(wantTrades, trades, cmp.AllowUnexported(move{})); diff != "" {
+		t.Errorf("tradeCycles(%v) trades diff (-want +got):\n%s", asg, diff)
+	}
+	if !a.paretoEfficient(got) {
+		t.Errorf("paretoEfficient(tradeCycles(%v)) got false; want true", asg)
+	}
+}
+
+func TestTradeCyclesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	for _, policy := range []unrankedPolicy{requireFullRankings, tiedLast, randomFallback, noAllocation} {
+		for trial := 0; trial < 200; trial++ {
+			a := randomAllocator(rng, policy)
+			if err := a.init(rng); err != nil {
+				t.Fatalf("%T.init() error %v", a, err)
+			}
+			o := a.newOrdering()
+			rng.Shuffle(len(o.order), o.swap)
+			asg := o.assignment()
+			desc := fmt.Sprintf("policy %q; available %v; preferences %v; assignment %v", policy, a.available, a.preferences, asg)
+
+			if got, want := a.paretoEfficient(asg), bruteForceParetoEfficient(a, asg); got != want {
+				t.Errorf("%s: paretoEfficient() got %t; brute force %t", desc, got, want)
+			}
+			if policy == requireFullRankings && !a.paretoEfficient(asg) {
+				t.Errorf("%s: serial dictatorship with full rankings not Pareto efficient", desc)
+			}
+
+			got, trades := a.tradeCycles(asg)
+			if !bruteForceParetoEfficient(a, got) {
+				t.Errorf("%s: tradeCycles() = %v not Pareto efficient", desc, got)
+			}
+			if (len(trades) == 0) != cmp.Equal(asg, got) {
+				t.Errorf("%s: tradeCycles() = %v with %d trades", desc, got, len(trades))
+			}
+
+			cs := a.costs()
+			allocated := make([]uint64, len(a.available))
+			for x, b := range got {
+				if b >= 0 {
+					allocated[b]++
+				}
+				if before, after := a.cost(cs, x, asg[x]), a.cost(cs, x, b); after < 0 || after > before {
+					t.Errorf("%s: tradeCycles() = %v; entrant %d cost %d -> %d", desc, got, x, before, after)
+				}
+			}
+			for b, n := range allocated {
+				if n > a.available[b] {
+					t.Errorf("%s: tradeCycles() = %v exceeds capacity of bucket %d", desc, got, b)
+				}
+			}
+			if before, after := o.Simulate(context.Background()), a.fitness(got); after < before {
+				t.Errorf("%s: tradeCycles() fitness %v < %v", desc, after, before)
+			}
+		}
+	}
+}
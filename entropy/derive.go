@@ -0,0 +1,57 @@
+package entropy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Fold hashes seed n times, reproducing the construction historically used by
+// raffle.sh to obtain multiple sub-seeds from a single block hash:
+//
+//	for i in $(seq 1 n); do
+//	    entropy=$(echo "${entropy}" | sha256sum | cut -f 1 -d " ")
+//	done
+//
+// where ${entropy} is initially the lowercase hex encoding of seed, without a
+// 0x prefix. Note that echo appends a newline, so each round computes the
+// SHA-256 of the 64 hex characters followed by '\n'; not of the 32 raw bytes.
+//
+// Fold is retained for the reproduction of historical raffles; new raffles
+// SHOULD use Derive() instead.
+func Fold(seed common.Hash, n int) common.Hash {
+	for i := 0; i < n; i++ {
+		seed = sha256.Sum256([]byte(hex.EncodeToString(seed[:]) + "\n"))
+	}
+	return seed
+}
+
+// DerivationDomain separates sub-seeds computed by Derive() from any other use
+// of the same seed.
+const DerivationDomain = "proofxyz/raffles/entropy.Derive/v1"
+
+// Derive returns a sub-seed of seed, identified by label, for use in raffles
+// requiring multiple independent draws from a single block hash. It is
+// computed as
+//
+//	HMAC-SHA256(key = seed, message = DerivationDomain || 0x00 || label)
+//
+// with seed as raw bytes and label as UTF-8, and can therefore be reproduced
+// with, for example:
+//
+//	printf '<DerivationDomain>\0<label>' | openssl dgst -sha256 -mac HMAC -macopt hexkey:<seed without 0x>
+//
+// Distinct labels produce independent sub-seeds. Unlike Fold(), sub-seeds
+// don't depend on each other so can be computed in any order.
+func Derive(seed common.Hash, label string) common.Hash {
+	mac := hmac.New(sha256.New, seed[:])
+	mac.Write([]byte(DerivationDomain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(label))
+
+	var sub common.Hash
+	copy(sub[:], mac.Sum(nil))
+	return sub
+}
@@ -0,0 +1,72 @@
+package entropy
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFold(t *testing.T) {
+	// Values computed with:
+	//
+	// a=8720201c09f25378a4d254c38f317317032dd2d9a00db450360b03dc55bff2ac
+	// for i in 1 2 3; do a=$(echo "$a" | sha256sum | cut -f1 -d' '); echo $a; done
+	seed := common.HexToHash("0x8720201c09f25378a4d254c38f317317032dd2d9a00db450360b03dc55bff2ac")
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{
+			n:    0,
+			want: "0x8720201c09f25378a4d254c38f317317032dd2d9a00db450360b03dc55bff2ac",
+		},
+		{
+			n:    1,
+			want: "0x9758ced0d322a8634987209ab5f10940d97f0929027147e196f59dc14ce38c87",
+		},
+		{
+			n:    2,
+			want: "0x09fb1ed89d9a26e9233e5ca22505f536c8615384337452411faa2da8c416f3ef",
+		},
+		{
+			n:    3,
+			want: "0xfff344506d72aa08762930fe1aa1e8ab00b864e8eeb287b67167bb0d6fc44d60",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Fold(seed, tt.n); got.Hex() != tt.want {
+			t.Errorf("Fold(%v, %d) got %v; want %s", seed, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	seed := common.HexToHash("0x8720201c09f25378a4d254c38f317317032dd2d9a00db450360b03dc55bff2ac")
+
+	// Computed with:
+	//
+	// printf 'proofxyz/raffles/entropy.Derive/v1\0staff-mint/1' | openssl dgst -sha256 -mac HMAC -macopt hexkey:8720201c…
+	const want = "0x5de297e18eef90bc2e1693412380309e136dc48c581d7082c1337d4c4e957510"
+	if got := Derive(seed, "staff-mint/1"); got.Hex() != want {
+		t.Errorf("Derive(%v, %q) got %v; want %s", seed, "staff-mint/1", got, want)
+	}
+
+	seen := map[common.Hash]string{seed: "seed"}
+	for _, label := range []string{"", "a", "b", "staff-mint/1", "staff-mint/2", "staff-mint/10", "staff-mint/1\x00"} {
+		got := Derive(seed, label)
+		if prev, ok := seen[got]; ok {
+			t.Errorf("Derive(%v, %q) collides with %q", seed, label, prev)
+		}
+		seen[got] = label
+
+		if again := Derive(seed, label); again != got {
+			t.Errorf("Derive(%v, %q) not deterministic; got %v then %v", seed, label, got, again)
+		}
+	}
+
+	if Derive(common.Hash{1}, "a") == Derive(common.Hash{2}, "a") {
+		t.Errorf("Derive() with different seeds and same label produced same sub-seed")
+	}
+}
@@ -0,0 +1,135 @@
+// Package entropy parses the entropy files committed to each raffle folder.
+//
+// An entropy file announces, in a comment, the future block whose hash will be
+// used as entropy, e.g. "# We will use the hash of block 17835750 as a random
+// seed". Once the block has been mined, its hash is added as the final line of
+// the file. Until then the file is considered pending.
+package entropy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FileName is the name of the entropy file present in each raffle folder.
+const FileName = "entropy"
+
+// A File is a parsed entropy file.
+type File struct {
+	// Block is the number of the block that was committed to.
+	Block uint64
+	// Hash is the hash of Block, or nil if the file is pending.
+	Hash *common.Hash
+	// Comments are all comment lines, excluding the leading # and surrounding
+	// whitespace, including the one from which Block was parsed.
+	Comments []string
+}
+
+// Pending returns whether the entropy is yet to be set.
+func (f *File) Pending() bool {
+	return f.Hash == nil
+}
+
+// Hex returns the hex-encoded hash, without a 0x prefix, as it appears in the
+// file. Hex panics if the file is pending.
+func (f *File) Hex() string {
+	return strings.TrimPrefix(f.Hash.Hex(), "0x")
+}
+
+var (
+	commitment = regexp.MustCompile(`\bhash of block (\d+)\b`)
+	hashLine   = regexp.MustCompile(`^0x[0-9a-f]{64}$`)
+)
+
+// ParseFile opens and parses the entropy file at path.
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	e, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return e, nil
+}
+
+// Parse parses an entropy file. Blank lines are ignored, and every other line
+// MUST be either a comment (beginning with #) or the hash. Exactly one comment
+// MUST commit to a block number, and the hash, if present, MUST be the last
+// non-blank line, exactly 32 bytes of lowercase hex with a 0x prefix, and have
+// no surrounding whitespace.
+func Parse(r io.Reader) (*File, error) {
+	var (
+		f          File
+		haveBlock  bool
+		hashLineNo int
+	)
+
+	s := bufio.NewScanner(r)
+	for lineNo := 1; s.Scan(); lineNo++ {
+		raw := s.Text()
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case line == "":
+			continue
+
+		case hashLineNo != 0:
+			return nil, fmt.Errorf("line %d: content after hash on line %d", lineNo, hashLineNo)
+
+		case strings.HasPrefix(line, "#"):
+			c := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			f.Comments = append(f.Comments, c)
+
+			m := commitment.FindStringSubmatch(c)
+			if m == nil {
+				continue
+			}
+			if haveBlock {
+				return nil, fmt.Errorf("line %d: multiple block commitments", lineNo)
+			}
+			n, err := strconv.ParseUint(m[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: block number %q: %v", lineNo, m[1], err)
+			}
+			f.Block = n
+			haveBlock = true
+
+		case hashLine.MatchString(line):
+			if raw != line {
+				return nil, fmt.Errorf("line %d: hash has surrounding whitespace", lineNo)
+			}
+			if !haveBlock {
+				return nil, fmt.Errorf("line %d: hash precedes block commitment", lineNo)
+			}
+			h := common.HexToHash(line)
+			f.Hash = &h
+			hashLineNo = lineNo
+
+		case strings.HasPrefix(line, "0x"):
+			return nil, fmt.Errorf("line %d: malformed hash %q; must be 32 bytes of lowercase hex", lineNo, line)
+
+		default:
+			return nil, fmt.Errorf("line %d: neither a comment nor a hash: %q", lineNo, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%T.Scan(): %v", s, err)
+	}
+
+	if !haveBlock {
+		return nil, errors.New("no block commitment; expecting a comment containing \"hash of block <number>\"")
+	}
+	return &f, nil
+}
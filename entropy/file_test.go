@@ -0,0 +1,169 @@
+package entropy
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testHash = "0xf51e982a1322f2f0b054bda01e38918efeffcfcd6d0436b88d9a0d2bc826634f"
+
+func TestParse(t *testing.T) {
+	hash := common.HexToHash(testHash)
+
+	tests := []struct {
+		name string
+		in   string
+		want *File
+	}{
+		{
+			name: "compact",
+			in:   "# We will use the hash of block 17835750 as a random seed\n" + testHash + "\n",
+			want: &File{
+				Block:    17835750,
+				Hash:     &hash,
+				Comments: []string{"We will use the hash of block 17835750 as a random seed"},
+			},
+		},
+		{
+			name: "blank lines and extra comments",
+			in:   "# We will use the hash of block 18778550 as random seed \n\n# https://etherscan.io/block/18778550\n\n" + testHash + "\n\n\n",
+			want: &File{
+				Block: 18778550,
+				Hash:  &hash,
+				Comments: []string{
+					"We will use the hash of block 18778550 as random seed",
+					"https://etherscan.io/block/18778550",
+				},
+			},
+		},
+		{
+			name: "no trailing newline",
+			in:   "# We use the hash of block 1 as random seed\n" + testHash,
+			want: &File{
+				Block:    1,
+				Hash:     &hash,
+				Comments: []string{"We use the hash of block 1 as random seed"},
+			},
+		},
+		{
+			name: "pending",
+			in:   "# We will use the hash of block 42 as a random seed\n\n",
+			want: &File{
+				Block:    42,
+				Comments: []string{"We will use the hash of block 42 as a random seed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatalf("Parse(%q) error %v", tt.in, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Parse(%q) diff (-want +got):\n%s", tt.in, diff)
+			}
+			if got, want := got.Pending(), tt.want.Hash == nil; got != want {
+				t.Errorf("Parse(%q).Pending() got %t; want %t", tt.in, got, want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const block = "# We will use the hash of block 1 as a random seed\n"
+
+	tests := []struct {
+		name, in, wantErrContaining string
+	}{
+		{
+			name:              "empty",
+			in:                "",
+			wantErrContaining: "no block commitment",
+		},
+		{
+			name:              "no commitment",
+			in:                "# A random seed\n" + testHash + "\n",
+			wantErrContaining: "precedes block commitment",
+		},
+		{
+			name:              "multiple commitments",
+			in:                block + block + testHash,
+			wantErrContaining: "multiple block commitments",
+		},
+		{
+			name:              "trailing whitespace",
+			in:                block + testHash + " \n",
+			wantErrContaining: "surrounding whitespace",
+		},
+		{
+			name:              "leading whitespace",
+			in:                block + "\t" + testHash + "\n",
+			wantErrContaining: "surrounding whitespace",
+		},
+		{
+			name:              "short hash",
+			in:                block + testHash[:64] + "\n",
+			wantErrContaining: "malformed hash",
+		},
+		{
+			name:              "upper-case hash",
+			in:                block + "0x" + strings.ToUpper(testHash[2:10]) + testHash[10:] + "\n",
+			wantErrContaining: "malformed hash",
+		},
+		{
+			name:              "no 0x prefix",
+			in:                block + testHash[2:] + "\n",
+			wantErrContaining: "neither a comment nor a hash",
+		},
+		{
+			name:              "content after hash",
+			in:                block + testHash + "\n\n# oops\n",
+			wantErrContaining: "content after hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrContaining) {
+				t.Errorf("Parse(%q) got err %v; want containing %q", tt.in, err, tt.wantErrContaining)
+			}
+		})
+	}
+}
+
+// TestRepositoryFiles ensures that every entropy file in the repository is
+// well formed.
+func TestRepositoryFiles(t *testing.T) {
+	var n int
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != FileName {
+			return nil
+		}
+		n++
+
+		if _, err := ParseFile(path); err != nil {
+			t.Error(err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("filepath.WalkDir() error %v", err)
+	}
+	if n == 0 {
+		t.Error("no entropy files found")
+	}
+}
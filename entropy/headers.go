@@ -0,0 +1,229 @@
+package entropy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// HeaderFiles are the names of files, saved next to an entropy file, from which
+// block headers are read for offline verification of the entropy; see
+// ReadHeaders().
+var HeaderFiles = []string{"headers.json", "headers.rlp"}
+
+// unsupportedHeaderFields are JSON fields of block headers that were introduced
+// by hard forks after the version of go-ethereum used by this module. As they
+// are included in the block hash, headers containing them can't be verified.
+var unsupportedHeaderFields = []string{
+	"blobGasUsed",
+	"excessBlobGas",
+	"parentBeaconBlockRoot",
+	"requestsHash",
+}
+
+// ErrNoHeaders is returned by FindHeaders() if none of HeaderFiles exist in
+// the directory.
+var ErrNoHeaders = errors.New("no block headers")
+
+// FindHeaders reads the headers from the first of HeaderFiles that exists in
+// dir, returning ErrNoHeaders if there are none.
+func FindHeaders(dir string) ([]*types.Header, error) {
+	for _, name := range HeaderFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		return ReadHeaders(path)
+	}
+	return nil, ErrNoHeaders
+}
+
+// ReadHeaders reads block headers from path, which MUST have either a .json or
+// .rlp extension; see DecodeHeadersJSON() and DecodeHeadersRLP() respectively.
+func ReadHeaders(path string) ([]*types.Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	var hs []*types.Header
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		hs, err = DecodeHeadersJSON(f)
+	case ".rlp":
+		hs, err = DecodeHeadersRLP(f)
+	default:
+		return nil, fmt.Errorf("unsupported header file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return hs, nil
+}
+
+// DecodeHeadersJSON decodes either a single header or an array of headers, in
+// the format returned by the eth_getBlockByNumber JSON-RPC method. The result
+// MAY remain wrapped in its JSON-RPC response. If a header includes its own
+// hash, it MUST equal the computed hash.
+func DecodeHeadersJSON(r io.Reader) ([]*types.Header, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Decode(): %v", err)
+	}
+
+	var rpc struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &rpc); err == nil && len(rpc.Result) > 0 {
+		raw = rpc.Result
+	}
+
+	var raws []json.RawMessage
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &raws); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(…, %T): %v", &raws, err)
+		}
+	} else {
+		raws = []json.RawMessage{raw}
+	}
+
+	var hs []*types.Header
+	for i, r := range raws {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(r, &fields); err != nil {
+			return nil, fmt.Errorf("header %d: json.Unmarshal(…, %T): %v", i, &fields, err)
+		}
+		for _, f := range unsupportedHeaderFields {
+			if _, ok := fields[f]; ok {
+				return nil, fmt.Errorf("header %d: field %q is unsupported by this version of go-ethereum", i, f)
+			}
+		}
+
+		h := new(types.Header)
+		if err := json.Unmarshal(r, h); err != nil {
+			return nil, fmt.Errorf("header %d: json.Unmarshal(…, %T): %v", i, h, err)
+		}
+		if claimed, ok := fields["hash"]; ok {
+			var want common.Hash
+			if err := json.Unmarshal(claimed, &want); err != nil {
+				return nil, fmt.Errorf("header %d: hash: %v", i, err)
+			}
+			if got := h.Hash(); got != want {
+				return nil, fmt.Errorf("header %d: computed hash %v != included hash %v", i, got, want)
+			}
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+// DecodeHeadersRLP decodes one or more concatenated, RLP-encoded headers. The
+// encoding MAY be either raw bytes, or hex encoded with a 0x prefix and one
+// header per line, as returned by the debug_getRawHeader JSON-RPC method.
+func DecodeHeadersRLP(r io.Reader) ([]*types.Header, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read headers: %v", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(buf), []byte("0x")) {
+		var raw []byte
+		s := bufio.NewScanner(bytes.NewReader(buf))
+		for s.Scan() {
+			l := strings.TrimSpace(s.Text())
+			if l == "" {
+				continue
+			}
+			b, err := hexutil.Decode(l)
+			if err != nil {
+				return nil, fmt.Errorf("hexutil.Decode(%q): %v", l, err)
+			}
+			raw = append(raw, b...)
+		}
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("%T.Scan(): %v", s, err)
+		}
+		buf = raw
+	}
+
+	var hs []*types.Header
+	s := rlp.NewStream(bytes.NewReader(buf), uint64(len(buf)))
+	for {
+		h := new(types.Header)
+		err := s.Decode(h)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("header %d: %T.Decode(): %v (headers from later hard forks are unsupported by this version of go-ethereum)", len(hs), s, err)
+		}
+		hs = append(hs, h)
+	}
+	if len(hs) == 0 {
+		return nil, errors.New("no headers")
+	}
+	return hs, nil
+}
+
+// Verify checks that the headers, in any order, form a contiguous chain in
+// which each is the parent of the next, and that they include the committed
+// block with a hash equal to the entropy.
+func (f *File) Verify(headers []*types.Header) error {
+	if f.Pending() {
+		return errors.New("entropy pending")
+	}
+	if len(headers) == 0 {
+		return errors.New("no headers")
+	}
+
+	// Numbers are validated before sorting, which would otherwise panic on a
+	// nil Number.
+	for i, h := range headers {
+		if h.Number == nil || !h.Number.IsUint64() {
+			return fmt.Errorf("header %d: invalid number %v", i, h.Number)
+		}
+	}
+	hs := append([]*types.Header{}, headers...)
+	sort.Slice(hs, func(i, j int) bool {
+		return hs[i].Number.Cmp(hs[j].Number) == -1
+	})
+
+	var committed *types.Header
+	for i, h := range hs {
+		if h.Number.Uint64() == f.Block {
+			committed = h
+		}
+		if i == 0 {
+			continue
+		}
+
+		parent := hs[i-1]
+		if got, want := h.Number.Uint64(), parent.Number.Uint64()+1; got != want {
+			return fmt.Errorf("headers not contiguous; block %d follows %d", got, want-1)
+		}
+		if got, want := h.ParentHash, parent.Hash(); got != want {
+			return fmt.Errorf("block %d parent hash %v != hash of block %d %v", h.Number, got, parent.Number, want)
+		}
+	}
+
+	if committed == nil {
+		return fmt.Errorf("committed block %d not in headers for blocks %v to %v", f.Block, hs[0].Number, hs[len(hs)-1].Number)
+	}
+	if got, want := committed.Hash(), *f.Hash; got != want {
+		return fmt.Errorf("hash of block %d is %v; entropy is %v", f.Block, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package entropy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testChain returns n contiguous, post-Shanghai headers starting at block
+// number first.
+func testChain(first uint64, n int) []*types.Header {
+	var hs []*types.Header
+	for i := 0; i < n; i++ {
+		h := &types.Header{
+			Number:          new(big.Int).SetUint64(first + uint64(i)),
+			Difficulty:      new(big.Int),
+			GasLimit:        30_000_000,
+			Time:            1_700_000_000 + 12*uint64(i),
+			Extra:           []byte("raffles"),
+			BaseFee:         big.NewInt(1e9),
+			WithdrawalsHash: &types.EmptyWithdrawalsHash,
+			UncleHash:       types.EmptyUncleHash,
+			TxHash:          types.EmptyTxsHash,
+			ReceiptHash:     types.EmptyReceiptsHash,
+		}
+		if i > 0 {
+			h.ParentHash = hs[i-1].Hash()
+		}
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+func committedTo(t *testing.T, h *types.Header) *File {
+	t.Helper()
+	in := fmt.Sprintf("# We will use the hash of block %d as a random seed\n\n%s\n", h.Number, h.Hash().Hex())
+	f, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Parse(%q) error %v", in, err)
+	}
+	return f
+}
+
+func TestVerify(t *testing.T) {
+	chain := testChain(17835748, 5)
+	f := committedTo(t, chain[2])
+
+	reversed := []*types.Header{chain[4], chain[3], chain[2], chain[1], chain[0]}
+	for name, hs := range map[string][]*types.Header{
+		"single":                {chain[2]},
+		"full chain":            chain,
+		"descendants only":      chain[2:],
+		"ancestors only":        chain[:3],
+		"order doesn't matter":  reversed,
+		"committed block first": {chain[2], chain[3]},
+	} {
+		if err := f.Verify(hs); err != nil {
+			t.Errorf("%s: %T.Verify() error %v", name, f, err)
+		}
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	chain := testChain(17835748, 5)
+	f := committedTo(t, chain[2])
+
+	tampered := testChain(17835748, 5)
+	tampered[1].Extra = []byte("tampered")
+
+	other := testChain(17835748, 5)
+	for _, h := range other {
+		h.Time++
+	}
+	fork := append(append([]*types.Header{}, chain[:2]...), other[2:]...)
+
+	nilNumber := types.CopyHeader(chain[3])
+	nilNumber.Number = nil
+
+	tests := []struct {
+		name              string
+		headers           []*types.Header
+		wantErrContaining string
+	}{
+		{
+			name:              "no headers",
+			wantErrContaining: "no headers",
+		},
+		{
+			name:              "committed block missing",
+			headers:           chain[3:],
+			wantErrContaining: "not in headers",
+		},
+		{
+			name:              "gap",
+			headers:           []*types.Header{chain[0], chain[2]},
+			wantErrContaining: "not contiguous",
+		},
+		{
+			name:              "broken parent chain",
+			headers:           append(append([]*types.Header{}, tampered[:2]...), chain[2:]...),
+			wantErrContaining: "parent hash",
+		},
+		{
+			name:              "different block at committed height",
+			headers:           []*types.Header{other[2]},
+			wantErrContaining: "entropy is",
+		},
+		{
+			name:              "fork",
+			headers:           fork,
+			wantErrContaining: "parent hash",
+		},
+		{
+			name:              "nil number",
+			headers:           []*types.Header{chain[2], nilNumber},
+			wantErrContaining: "invalid number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.Verify(tt.headers)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrContaining) {
+				t.Errorf("%T.Verify() got err %v; want containing %q", f, err, tt.wantErrContaining)
+			}
+		})
+	}
+
+	pending := &File{Block: 1}
+	if err := pending.Verify(chain); err == nil {
+		t.Errorf("%T.Verify() of pending entropy got nil error", pending)
+	}
+}
+
+func TestDecodeHeaders(t *testing.T) {
+	chain := testChain(18778549, 3)
+
+	single, err := json.Marshal(chain[1])
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", chain[1], err)
+	}
+	array, err := json.Marshal(chain)
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", chain, err)
+	}
+	rpcResponse := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%s}`, single)
+
+	var rawRLP bytes.Buffer
+	var hexRLP []string
+	for _, h := range chain {
+		buf, err := rlp.EncodeToBytes(h)
+		if err != nil {
+			t.Fatalf("rlp.EncodeToBytes(%T) error %v", h, err)
+		}
+		rawRLP.Write(buf)
+		hexRLP = append(hexRLP, fmt.Sprintf("%#x", buf))
+	}
+
+	tests := []struct {
+		name   string
+		decode func() ([]*types.Header, error)
+		want   []common.Hash
+	}{
+		{
+			name:   "JSON object",
+			decode: func() ([]*types.Header, error) { return DecodeHeadersJSON(bytes.NewReader(single)) },
+			want:   []common.Hash{chain[1].Hash()},
+		},
+		{
+			name:   "JSON array",
+			decode: func() ([]*types.Header, error) { return DecodeHeadersJSON(bytes.NewReader(array)) },
+			want:   []common.Hash{chain[0].Hash(), chain[1].Hash(), chain[2].Hash()},
+		},
+		{
+			name:   "JSON-RPC response",
+			decode: func() ([]*types.Header, error) { return DecodeHeadersJSON(strings.NewReader(rpcResponse)) },
+			want:   []common.Hash{chain[1].Hash()},
+		},
+		{
+			name:   "raw RLP",
+			decode: func() ([]*types.Header, error) { return DecodeHeadersRLP(bytes.NewReader(rawRLP.Bytes())) },
+			want:   []common.Hash{chain[0].Hash(), chain[1].Hash(), chain[2].Hash()},
+		},
+		{
+			name: "hex RLP",
+			decode: func() ([]*types.Header, error) {
+				return DecodeHeadersRLP(strings.NewReader(strings.Join(hexRLP, "\n") + "\n"))
+			},
+			want: []common.Hash{chain[0].Hash(), chain[1].Hash(), chain[2].Hash()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs, err := tt.decode()
+			if err != nil {
+				t.Fatalf("decode error %v", err)
+			}
+			var got []common.Hash
+			for _, h := range hs {
+				got = append(got, h.Hash())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decoded header hashes diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeHeadersJSONErrors(t *testing.T) {
+	h := testChain(1, 1)[0]
+	buf, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", h, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		t.Fatalf("json.Unmarshal() error %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		modify            func(map[string]interface{})
+		wantErrContaining string
+	}{
+		{
+			name: "incorrect hash",
+			modify: func(f map[string]interface{}) {
+				f["hash"] = common.Hash{}.Hex()
+			},
+			wantErrContaining: "computed hash",
+		},
+		{
+			name: "post-Cancun field",
+			modify: func(f map[string]interface{}) {
+				f["parentBeaconBlockRoot"] = common.Hash{}.Hex()
+			},
+			wantErrContaining: "unsupported",
+		},
+		{
+			name: "missing required field",
+			modify: func(f map[string]interface{}) {
+				delete(f, "stateRoot")
+			},
+			wantErrContaining: "stateRoot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := make(map[string]interface{})
+			for k, v := range fields {
+				f[k] = v
+			}
+			tt.modify(f)
+			in, err := json.Marshal(f)
+			if err != nil {
+				t.Fatalf("json.Marshal() error %v", err)
+			}
+
+			_, err = DecodeHeadersJSON(bytes.NewReader(in))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrContaining) {
+				t.Errorf("DecodeHeadersJSON(%s) got err %v; want containing %q", in, err, tt.wantErrContaining)
+			}
+		})
+	}
+}
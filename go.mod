@@ -3,16 +3,17 @@ module github.com/proofxyz/raffles
 go 1.19
 
 require (
-	github.com/ccssmnn/hego v0.0.0-20220220103052-7e3e58211887
 	github.com/ethereum/go-ethereum v1.11.6
 	github.com/gocarina/gocsv v0.0.0-20230406101422-6445c2b15027
 	github.com/golang/glog v1.1.1
 	github.com/google/go-cmp v0.5.9
-	github.com/holiman/uint256 v1.2.2
 	github.com/soypat/mu8 v0.7.1
 )
 
 require (
+	github.com/btcsuite/btcd/btcec/v2 v2.2.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	github.com/holiman/uint256 v1.2.2 // indirect
 	golang.org/x/crypto v0.1.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 )
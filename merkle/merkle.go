@@ -0,0 +1,202 @@
+// Package merkle builds keccak256 Merkle trees over the entries of raffle
+// lists, allowing a single root to be published for a list and each entry to
+// be proven, with its multiplicity, to have been included in it.
+//
+// Each distinct value of a list is a single leaf, computed as
+//
+//	keccak256(0x00 || uint64_be(multiplicity) || value)
+//
+// and each internal node as
+//
+//	keccak256(0x01 || min(a, b) || max(a, b))
+//
+// where a and b are the child nodes, ordered bytewise. The prefixes prevent a
+// node from being presented as a leaf, and sorting each pair allows a proof to
+// be verified without knowledge of the position of the leaf. Leaves are
+// ordered by value, and the last node of a level with an odd number of nodes
+// is promoted, unchanged, to the next level.
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// An Entry is a distinct value of a list, and the number of times that it
+// appears.
+type Entry struct {
+	Value        string `json:"value"`
+	Multiplicity uint64 `json:"multiplicity"`
+}
+
+// Entries groups the lines, which MUST be sorted (see shuffle.SortedNonEmpty()),
+// into Entries ordered by value.
+func Entries(lines [][]byte) []Entry {
+	var es []Entry
+	for i, l := range lines {
+		if i > 0 && bytes.Equal(l, lines[i-1]) {
+			es[len(es)-1].Multiplicity++
+			continue
+		}
+		es = append(es, Entry{Value: string(l), Multiplicity: 1})
+	}
+	return es
+}
+
+// Leaf returns the leaf node of the Entry.
+func Leaf(e Entry) common.Hash {
+	var mult [8]byte
+	binary.BigEndian.PutUint64(mult[:], e.Multiplicity)
+	return crypto.Keccak256Hash([]byte{0}, mult[:], []byte(e.Value))
+}
+
+// parent returns the internal node with children a and b, in either order.
+func parent(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) == 1 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash([]byte{1}, a[:], b[:])
+}
+
+// A Tree is a Merkle tree over the Entries of a list.
+type Tree struct {
+	entries []Entry
+	// levels[0] are the leaves and the last level is the root.
+	levels [][]common.Hash
+}
+
+// New builds a Tree over the Entries, which MUST be non-empty, have distinct
+// values sorted bytewise, and non-zero multiplicities, as returned by
+// Entries().
+func New(entries []Entry) (*Tree, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("no entries")
+	}
+	leaves := make([]common.Hash, len(entries))
+	for i, e := range entries {
+		if e.Multiplicity == 0 {
+			return nil, fmt.Errorf("entry %d (%q) has zero multiplicity", i, e.Value)
+		}
+		if i > 0 && e.Value <= entries[i-1].Value {
+			return nil, fmt.Errorf("entry %d (%q) not strictly after %q", i, e.Value, entries[i-1].Value)
+		}
+		leaves[i] = Leaf(e)
+	}
+
+	t := &Tree{
+		entries: entries,
+		levels:  [][]common.Hash{leaves},
+	}
+	for level := leaves; len(level) > 1; {
+		var next []common.Hash
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, parent(level[i], level[i+1]))
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t, nil
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() common.Hash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Entries returns the Entries over which the tree was built.
+func (t *Tree) Entries() []Entry {
+	return t.entries
+}
+
+// Find returns the index of the Entry with the value, and whether it exists.
+func (t *Tree) Find(value string) (int, bool) {
+	i := sort.Search(len(t.entries), func(i int) bool {
+		return t.entries[i].Value >= value
+	})
+	return i, i < len(t.entries) && t.entries[i].Value == value
+}
+
+// Proof returns the inclusion proof of the i-th Entry; i.e. the siblings of
+// all nodes on the path from its leaf to the root, excluding those promoted
+// without a sibling.
+func (t *Tree) Proof(i int) []common.Hash {
+	proof := []common.Hash{}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sib := i ^ 1; sib < len(level) {
+			proof = append(proof, level[sib])
+		}
+		i /= 2
+	}
+	return proof
+}
+
+// Verify returns whether the proof shows that the Entry is included in the
+// tree with the root.
+func Verify(root common.Hash, e Entry, proof []common.Hash) bool {
+	n := Leaf(e)
+	for _, p := range proof {
+		n = parent(n, p)
+	}
+	return n == root
+}
+
+// Scheme identifies the construction of leaves and nodes described in the
+// package documentation.
+const Scheme = "proofxyz/raffles/merkle/v1"
+
+// RootsFile is the name of the file, written to a raffle folder, that holds
+// the Roots of its lists.
+const RootsFile = "merkle.json"
+
+// Roots are the Merkle roots of all lists of a raffle folder.
+type Roots struct {
+	Scheme string     `json:"scheme"`
+	Lists  []ListRoot `json:"lists"`
+}
+
+// A ListRoot is the Merkle root of a single list.
+type ListRoot struct {
+	// List is the path of the list, relative to the raffle folder.
+	List string      `json:"list"`
+	Root common.Hash `json:"root"`
+	// Entries is the number of distinct values, i.e. leaves, and Lines the
+	// total number of entries drawn from, i.e. the sum of multiplicities.
+	Entries int    `json:"entries"`
+	Lines   uint64 `json:"lines"`
+}
+
+// A Proof is the JSON-encodable inclusion proof of an Entry in a list.
+type Proof struct {
+	List string      `json:"list"`
+	Root common.Hash `json:"root"`
+	Entry
+	Leaf  common.Hash   `json:"leaf"`
+	Proof []common.Hash `json:"proof"`
+}
+
+// ExportProof returns the Proof of the i-th Entry of the tree over the list.
+func (t *Tree) ExportProof(list string, i int) Proof {
+	e := t.entries[i]
+	return Proof{
+		List:  list,
+		Root:  t.Root(),
+		Entry: e,
+		Leaf:  Leaf(e),
+		Proof: t.Proof(i),
+	}
+}
+
+// Verify returns whether the proof is valid for its root.
+func (p Proof) Verify() bool {
+	return Verify(p.Root, p.Entry, p.Proof)
+}
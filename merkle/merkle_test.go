@@ -0,0 +1,136 @@
+package merkle
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEntries(t *testing.T) {
+	var lines [][]byte
+	for _, l := range []string{"a", "a", "b", "c", "c", "c"} {
+		lines = append(lines, []byte(l))
+	}
+	want := []Entry{
+		{Value: "a", Multiplicity: 2},
+		{Value: "b", Multiplicity: 1},
+		{Value: "c", Multiplicity: 3},
+	}
+	if diff := cmp.Diff(want, Entries(lines)); diff != "" {
+		t.Errorf("Entries(%q) diff (-want +got):\n%s", lines, diff)
+	}
+}
+
+func TestLeaf(t *testing.T) {
+	// keccak256(0x00 || 0x0000000000000002 || "a")
+	want := crypto.Keccak256Hash(common.FromHex("0x00000000000000000261"))
+	if got := Leaf(Entry{Value: "a", Multiplicity: 2}); got != want {
+		t.Errorf("Leaf(a ×2) got %v; want %v", got, want)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	es := []Entry{{"a", 1}, {"b", 2}, {"c", 3}}
+	l := func(i int) common.Hash { return Leaf(es[i]) }
+
+	tree, err := New(es)
+	if err != nil {
+		t.Fatalf("New(%v) error %v", es, err)
+	}
+	// The odd leaf, c, is promoted.
+	if got, want := tree.Root(), parent(parent(l(0), l(1)), l(2)); got != want {
+		t.Errorf("%T.Root() got %v; want %v", tree, got, want)
+	}
+
+	single, err := New(es[:1])
+	if err != nil {
+		t.Fatalf("New(%v) error %v", es[:1], err)
+	}
+	if got, want := single.Root(), l(0); got != want {
+		t.Errorf("%T.Root() of single entry got %v; want leaf %v", single, got, want)
+	}
+	if p := single.Proof(0); len(p) != 0 {
+		t.Errorf("%T.Proof(0) of single entry got %v; want empty", single, p)
+	}
+}
+
+func TestProofs(t *testing.T) {
+	for n := 1; n <= 33; n++ {
+		var es []Entry
+		for i := 0; i < n; i++ {
+			es = append(es, Entry{Value: fmt.Sprintf("%03d", i), Multiplicity: uint64(i%3 + 1)})
+		}
+		tree, err := New(es)
+		if err != nil {
+			t.Fatalf("New(%d entries) error %v", n, err)
+		}
+		root := tree.Root()
+
+		for i, e := range es {
+			if j, ok := tree.Find(e.Value); !ok || j != i {
+				t.Errorf("%d entries: %T.Find(%q) got %d, %t; want %d, true", n, tree, e.Value, j, ok, i)
+			}
+
+			proof := tree.Proof(i)
+			if !Verify(root, e, proof) {
+				t.Errorf("%d entries: Verify(%v, %+v, %T.Proof(%d)) got false; want true", n, root, e, tree, i)
+			}
+
+			wrong := e
+			wrong.Multiplicity++
+			if Verify(root, wrong, proof) {
+				t.Errorf("%d entries: Verify() with incorrect multiplicity %+v got true", n, wrong)
+			}
+			if Verify(root, Entry{Value: e.Value + "x", Multiplicity: e.Multiplicity}, proof) {
+				t.Errorf("%d entries: Verify() with incorrect value got true", n)
+			}
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, es := range [][]Entry{
+		nil,
+		{{"a", 0}},
+		{{"b", 1}, {"a", 1}},
+		{{"a", 1}, {"a", 1}},
+	} {
+		if _, err := New(es); err == nil {
+			t.Errorf("New(%v) got nil error", es)
+		}
+	}
+
+	tree, err := New([]Entry{{"a", 1}})
+	if err != nil {
+		t.Fatalf("New() error %v", err)
+	}
+	if _, ok := tree.Find("b"); ok {
+		t.Errorf("%T.Find(missing) got true", tree)
+	}
+}
+
+func TestProofJSON(t *testing.T) {
+	tree, err := New([]Entry{{"a", 1}, {"b", 2}, {"c", 3}})
+	if err != nil {
+		t.Fatalf("New() error %v", err)
+	}
+	buf, err := json.Marshal(tree.ExportProof("participants", 1))
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", Proof{}, err)
+	}
+
+	var got Proof
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error %v", buf, err)
+	}
+	if !got.Verify() {
+		t.Errorf("%T.Verify() after JSON round trip of %s got false", got, buf)
+	}
+	if got.Value != "b" || got.Multiplicity != 2 {
+		t.Errorf("%T got entry %+v; want b ×2", got, got.Entry)
+	}
+}
@@ -0,0 +1,177 @@
+package raffle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CommitmentFile is the name of the file, written next to a manifest when its
+// entropy block is announced, that commits to the contents of the manifest
+// itself and all of its lists. It is in the format output by `sha256sum`, and
+// can therefore be verified with `sha256sum -c commitment` from the raffle
+// folder.
+const CommitmentFile = "commitment"
+
+// ErrNoCommitment is returned by VerifyCommitment() if the manifest's folder has
+// no CommitmentFile.
+var ErrNoCommitment = errors.New("no commitment")
+
+// ErrCommitmentMismatch is returned, wrapped, by VerifyCommitment() if the
+// manifest or any of its lists have changed since being committed to.
+var ErrCommitmentMismatch = errors.New("file doesn't match commitment")
+
+// A Commitment maps the path of the manifest and of each list, relative to the
+// manifest and slash-separated, to the SHA-256 of its contents.
+type Commitment map[string][sha256.Size]byte
+
+// CommitmentPath returns the path to the manifest's CommitmentFile.
+func (m *Manifest) CommitmentPath() string {
+	return filepath.Join(m.Dir, CommitmentFile)
+}
+
+// committedFiles returns the paths of the manifest and all of its lists,
+// relative to the manifest, sorted and without duplicates. The manifest is
+// included so that its draws, e.g. the number of winners, can't be changed
+// either.
+func (m *Manifest) committedFiles() []string {
+	seen := map[string]bool{ManifestFile: true}
+	ls := []string{ManifestFile}
+	for _, d := range m.AllDraws() {
+		l := filepath.ToSlash(filepath.Clean(d.List))
+		if !seen[l] {
+			seen[l] = true
+			ls = append(ls, l)
+		}
+	}
+	sort.Strings(ls)
+	return ls
+}
+
+// Commitment computes the current Commitment to the manifest and its lists.
+func (m *Manifest) Commitment() (Commitment, error) {
+	c := make(Commitment)
+	for _, l := range m.committedFiles() {
+		path := filepath.Join(m.Dir, filepath.FromSlash(l))
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile(%q): %v", path, err)
+		}
+		c[l] = sha256.Sum256(buf)
+	}
+	return c, nil
+}
+
+// WriteCommitment writes the current Commitment to the manifest's
+// CommitmentFile.
+func (m *Manifest) WriteCommitment() error {
+	c, err := m.Commitment()
+	if err != nil {
+		return err
+	}
+	path := m.CommitmentPath()
+	if err := os.WriteFile(path, c.Marshal(), 0644); err != nil {
+		return fmt.Errorf("os.WriteFile(%q): %v", path, err)
+	}
+	return nil
+}
+
+// VerifyCommitment confirms that the manifest and its lists match those
+// committed to in its CommitmentFile, returning ErrNoCommitment if there is no
+// such file. A commitment that doesn't include the manifest and all of its
+// lists is an error.
+func (m *Manifest) VerifyCommitment() error {
+	path := m.CommitmentPath()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ErrNoCommitment
+	}
+	if err != nil {
+		return fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	committed, err := ParseCommitment(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	current, err := m.Commitment()
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for _, l := range m.committedFiles() {
+		want, ok := committed[l]
+		if !ok {
+			return fmt.Errorf("%s: %q not committed to", path, l)
+		}
+		if current[l] != want {
+			changed = append(changed, l)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %q in %q", ErrCommitmentMismatch, changed, m.Dir)
+	}
+	return nil
+}
+
+// Marshal returns the Commitment in the format output by `sha256sum`, sorted by
+// path.
+func (c Commitment) Marshal() []byte {
+	paths := make([]string, 0, len(c))
+	for p := range c {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	for _, p := range paths {
+		h := c[p]
+		fmt.Fprintf(&buf, "%x  %s\n", h[:], p)
+	}
+	return buf.Bytes()
+}
+
+// ParseCommitment parses the output of `sha256sum`, in either text or binary
+// mode, ignoring blank lines.
+func ParseCommitment(r io.Reader) (Commitment, error) {
+	c := make(Commitment)
+	s := bufio.NewScanner(r)
+	for lineNo := 1; s.Scan(); lineNo++ {
+		l := s.Text()
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		sum, path, ok := strings.Cut(l, " ")
+		path = strings.TrimPrefix(path, " ")
+		path = strings.TrimPrefix(path, "*")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("line %d: want `<sha256>  <path>`; got %q", lineNo, l)
+		}
+		b, err := hex.DecodeString(sum)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid SHA-256 %q", lineNo, sum)
+		}
+		path = filepath.ToSlash(filepath.Clean(path))
+		if _, ok := c[path]; ok {
+			return nil, fmt.Errorf("line %d: duplicate path %q", lineNo, path)
+		}
+		var h [sha256.Size]byte
+		copy(h[:], b)
+		c[path] = h
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%T.Scan(): %v", s, err)
+	}
+	return c, nil
+}
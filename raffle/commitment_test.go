@@ -0,0 +1,155 @@
+package raffle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitment(t *testing.T) {
+	const (
+		pending  = "# We will use the hash of block 1 as a random seed\n"
+		manifest = `{
+			"draws": [
+				{"list": "participants", "winners": 1},
+				{"list": "./participants", "winners": 1, "derive": "again"},
+				{"list": "more/participants", "winners": 1}
+			]
+		}`
+	)
+	dir := writeFiles(t, map[string]string{
+		ManifestFile:        manifest,
+		"entropy":           pending,
+		"participants":      "a\nb\n",
+		"more/participants": "c\n",
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if err := m.VerifyCommitment(); !errors.Is(err, ErrNoCommitment) {
+		t.Errorf("%T.VerifyCommitment() before writing got err %v; want %v", m, err, ErrNoCommitment)
+	}
+	if err := m.WriteCommitment(); err != nil {
+		t.Fatalf("%T.WriteCommitment() error %v", m, err)
+	}
+
+	// As output by `sha256sum more/participants participants raffle.json`.
+	want := "a3a5e715f0cc574a73c3f9bebb6bc24f32ffd5b67b387244c2c909da779a1478  more/participants\n" +
+		"911169ddaaf146aff539f58c26c489af3b892dff0fe283c1c264c65ae5aa59a2  participants\n" +
+		fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(manifest)), ManifestFile)
+	got, err := os.ReadFile(m.CommitmentPath())
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", m.CommitmentPath(), err)
+	}
+	if string(got) != want {
+		t.Errorf("%s got:\n%s\nwant:\n%s", CommitmentFile, got, want)
+	}
+	if err := m.VerifyCommitment(); err != nil {
+		t.Errorf("%T.VerifyCommitment() after writing error %v", m, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "entropy"), []byte(testEntropy), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error %v", err)
+	}
+	if _, err := m.Run(); err != nil {
+		t.Errorf("%T.Run() with matching commitment error %v", m, err)
+	}
+
+	// Even a change that doesn't alter the draw breaks the commitment.
+	if err := os.WriteFile(filepath.Join(dir, "more/participants"), []byte("c\n\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error %v", err)
+	}
+	if err := m.VerifyCommitment(); !errors.Is(err, ErrCommitmentMismatch) || !strings.Contains(err.Error(), "more/participants") {
+		t.Errorf("%T.VerifyCommitment() after modifying list got err %v; want %v naming the list", m, err, ErrCommitmentMismatch)
+	}
+	if _, err := m.Run(); !errors.Is(err, ErrCommitmentMismatch) {
+		t.Errorf("%T.Run() after modifying list got err %v; want %v", m, err, ErrCommitmentMismatch)
+	}
+}
+
+func TestCommitmentToManifest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFile:   `{"draws": [{"list": "participants", "winners": 1}]}`,
+		"entropy":      "# We will use the hash of block 1 as a random seed\n",
+		"participants": "a\nb\n",
+	})
+	path := filepath.Join(dir, ManifestFile)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if err := m.WriteCommitment(); err != nil {
+		t.Fatalf("%T.WriteCommitment() error %v", m, err)
+	}
+
+	// Changing the number of winners after committing MUST be detected.
+	if err := os.WriteFile(path, []byte(`{"draws": [{"list": "participants", "winners": 2}]}`), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error %v", err)
+	}
+	if m, err = Load(path); err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if err := m.VerifyCommitment(); !errors.Is(err, ErrCommitmentMismatch) || !strings.Contains(err.Error(), ManifestFile) {
+		t.Errorf("%T.VerifyCommitment() after modifying manifest got err %v; want %v naming the manifest", m, err, ErrCommitmentMismatch)
+	}
+}
+
+func TestCommitmentMissingList(t *testing.T) {
+	const manifest = `{"draws": [{"list": "participants", "winners": 1}, {"list": "other", "winners": 1}]}`
+	dir := writeFiles(t, map[string]string{
+		ManifestFile:   manifest,
+		"entropy":      testEntropy,
+		"participants": "a\nb\n",
+		"other":        "c\n",
+		CommitmentFile: "911169ddaaf146aff539f58c26c489af3b892dff0fe283c1c264c65ae5aa59a2  participants\n" +
+			fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(manifest)), ManifestFile),
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if err := m.VerifyCommitment(); err == nil || !strings.Contains(err.Error(), `"other" not committed`) {
+		t.Errorf("%T.VerifyCommitment() with uncommitted list got err %v; want not committed", m, err)
+	}
+	if _, err := m.Run(); err == nil {
+		t.Errorf("%T.Run() with uncommitted list got nil error", m)
+	}
+}
+
+func TestParseCommitment(t *testing.T) {
+	const sum = "911169ddaaf146aff539f58c26c489af3b892dff0fe283c1c264c65ae5aa59a2"
+
+	for _, in := range []string{
+		sum + "  participants\n",
+		sum + " *participants\n",
+		"\n" + sum + "  ./participants\n\n",
+	} {
+		c, err := ParseCommitment(strings.NewReader(in))
+		if err != nil {
+			t.Errorf("ParseCommitment(%q) error %v", in, err)
+			continue
+		}
+		if got := c.Marshal(); string(got) != sum+"  participants\n" {
+			t.Errorf("ParseCommitment(%q).Marshal() got %q", in, got)
+		}
+	}
+
+	for _, in := range []string{
+		sum,
+		sum + "  ",
+		sum[2:] + "  participants",
+		"0x" + sum + "  participants",
+		sum + "  participants\n" + sum + "  participants\n",
+	} {
+		if _, err := ParseCommitment(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseCommitment(%q) got nil error", in)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package raffle
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/proofxyz/raffles/shuffle"
+)
+
+// A Problem is an issue with a list, as found by LintList().
+type Problem struct {
+	Msg string
+	// Lines are the 1-indexed numbers of all offending lines, if the problem
+	// isn't with the list as a whole.
+	Lines []int
+	// Fixable problems are removed by CanonicalList() without altering any draw
+	// from the list.
+	Fixable bool
+}
+
+// String returns the problem, prefixed with up to 3 offending line numbers.
+func (p Problem) String() string {
+	const maxLines = 3
+	switch n := len(p.Lines); {
+	case n == 0:
+		return p.Msg
+	case n == 1:
+		return fmt.Sprintf("line %d: %s", p.Lines[0], p.Msg)
+	case n <= maxLines:
+		return fmt.Sprintf("lines %s: %s", joinInts(p.Lines), p.Msg)
+	default:
+		return fmt.Sprintf("lines %s and %d more: %s", joinInts(p.Lines[:maxLines]), n-maxLines, p.Msg)
+	}
+}
+
+func joinInts(is []int) string {
+	s := make([]string, len(is))
+	for i, v := range is {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// AllDraws returns the manifest's Draws, or the Draw of each of its Tiers.
+func (m *Manifest) AllDraws() []Draw {
+	if len(m.Tiers) == 0 {
+		return m.Draws
+	}
+	ds := make([]Draw, len(m.Tiers))
+	for i, t := range m.Tiers {
+		ds[i] = t.Draw
+	}
+	return ds
+}
+
+// ExpectsDuplicates returns whether duplicate entries in the Draw's list are
+// intentional.
+func (d Draw) ExpectsDuplicates() bool {
+	return d.Duplicates || d.Weighted || d.Dedupe == DedupeDistinct
+}
+
+// entryKind classifies the entries of a list.
+type entryKind int
+
+const (
+	addressEntry entryKind = iota
+	idEntry
+	otherEntry
+)
+
+func kindOf(v string) entryKind {
+	switch {
+	case common.IsHexAddress(v):
+		return addressEntry
+	case v != "" && strings.Trim(v, "0123456789") == "":
+		return idEntry
+	default:
+		return otherEntry
+	}
+}
+
+// LintList returns all problems with buf, the contents of the list of the
+// Draw, in a deterministic order. Values of weighted lists are checked without
+// their weights.
+func LintList(buf []byte, d Draw) []Problem {
+	var (
+		blank, whitespace, invalid, checksum []int
+		kinds                                [3]int
+	)
+	counts := make(map[string]int)
+	capitalisations := make(map[common.Address]map[string]bool)
+
+	lines := strings.Split(string(buf), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		num := i + 1
+		v := strings.TrimSpace(l)
+		if v == "" {
+			blank = append(blank, num)
+			continue
+		}
+		if v != l {
+			whitespace = append(whitespace, num)
+		}
+		if d.Weighted {
+			if idx := strings.LastIndexByte(v, ','); idx != -1 {
+				v = strings.TrimSpace(v[:idx])
+			}
+		}
+		counts[v]++
+
+		k := kindOf(v)
+		kinds[k]++
+		if k != addressEntry {
+			if strings.HasPrefix(strings.ToLower(v), "0x") {
+				invalid = append(invalid, num)
+			}
+			continue
+		}
+
+		// The 0x prefix is optional, as for common.IsHexAddress().
+		addr, hex := common.HexToAddress(v), v
+		if strings.HasPrefix(strings.ToLower(v), "0x") {
+			hex = v[2:]
+		}
+		if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && hex != addr.Hex()[2:] {
+			checksum = append(checksum, num)
+		}
+		if capitalisations[addr] == nil {
+			capitalisations[addr] = make(map[string]bool)
+		}
+		capitalisations[addr][v] = true
+	}
+
+	var ps []Problem
+	add := func(lines []int, fixable bool, msg string) {
+		if len(lines) > 0 {
+			ps = append(ps, Problem{Msg: msg, Lines: lines, Fixable: fixable})
+		}
+	}
+	add(blank, true, "blank line")
+	add(whitespace, true, "leading or trailing whitespace")
+	add(invalid, false, "invalid hex address")
+	add(checksum, false, "invalid EIP-55 checksum in mixed-case address")
+
+	var dups []string
+	for v, n := range counts {
+		if n > 1 {
+			dups = append(dups, v)
+		}
+	}
+	if len(dups) > 0 && !d.ExpectsDuplicates() {
+		sort.Strings(dups)
+		ps = append(ps, Problem{
+			Msg: fmt.Sprintf("%d duplicated value(s), e.g. %q ×%d; set \"duplicates\" in the manifest if intentional", len(dups), dups[0], counts[dups[0]]),
+		})
+	}
+
+	var recapitalised []string
+	for addr, vs := range capitalisations {
+		if len(vs) > 1 {
+			recapitalised = append(recapitalised, addr.Hex())
+		}
+	}
+	if len(recapitalised) > 0 {
+		sort.Strings(recapitalised)
+		ps = append(ps, Problem{
+			Msg: fmt.Sprintf("%d address(es) with differently capitalised entries, treated as different values by the draw, e.g. %s", len(recapitalised), recapitalised[0]),
+		})
+	}
+
+	var mixed []string
+	for k, name := range []string{"address(es)", "numeric ID(s)", "other value(s)"} {
+		if n := kinds[k]; n > 0 {
+			mixed = append(mixed, fmt.Sprintf("%d %s", n, name))
+		}
+	}
+	if len(mixed) > 1 {
+		ps = append(ps, Problem{Msg: "mixed content: " + strings.Join(mixed, ", ")})
+	}
+	return ps
+}
+
+// CanonicalList returns buf, the contents of a list, with surrounding
+// whitespace trimmed from every line, blank lines removed, and a trailing
+// newline. As shuffle.SortedNonEmpty() and shuffle.SortedWeighted() perform
+// the same normalisation, draws from the list are unaffected.
+func CanonicalList(buf []byte) []byte {
+	var out bytes.Buffer
+	for _, l := range bytes.Split(buf, []byte("\n")) {
+		if l = bytes.TrimSpace(l); len(l) > 0 {
+			out.Write(l)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
+
+// SameDraws returns whether lists a and b are drawn identically; i.e. whether
+// they have the same values, with the same multiplicity.
+func SameDraws(a, b []byte, d Draw) (bool, error) {
+	read := shuffle.SortedNonEmpty
+	if d.Weighted {
+		read = shuffle.SortedWeighted
+	}
+	la, err := read(bytes.NewReader(a))
+	if err != nil {
+		return false, err
+	}
+	lb, err := read(bytes.NewReader(b))
+	if err != nil {
+		return false, err
+	}
+	if len(la) != len(lb) {
+		return false, nil
+	}
+	for i := range la {
+		if !bytes.Equal(la[i], lb[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,171 @@
+package raffle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLintList(t *testing.T) {
+	const (
+		lower     = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+		checksum  = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+		badSum    = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"
+		otherAddr = "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+	)
+
+	tests := []struct {
+		name string
+		list string
+		draw Draw
+		want []Problem
+	}{
+		{
+			name: "clean addresses",
+			list: lower + "\n" + otherAddr + "\n",
+		},
+		{
+			name: "clean IDs without trailing newline",
+			list: "1\n25\n9600",
+		},
+		{
+			name: "blank lines and whitespace",
+			list: "\n1\n\n 2\n3\r\n",
+			want: []Problem{
+				{Msg: "blank line", Lines: []int{1, 3}, Fixable: true},
+				{Msg: "leading or trailing whitespace", Lines: []int{4, 5}, Fixable: true},
+			},
+		},
+		{
+			name: "invalid addresses",
+			list: "0x1234\n" + otherAddr + "\n" + badSum + "\n0xzzaeb6053f3e94c9b9a09f33669435e7ef1beaed\n",
+			want: []Problem{
+				{Msg: "invalid hex address", Lines: []int{1, 4}},
+				{Msg: "invalid EIP-55 checksum in mixed-case address", Lines: []int{3}},
+				{Msg: "mixed content: 2 address(es), 2 other value(s)"},
+			},
+		},
+		{
+			name: "unprefixed mixed-case addresses",
+			list: strings.TrimPrefix(otherAddr, "0x") + "\n" + strings.TrimPrefix(badSum, "0x") + "\n",
+			want: []Problem{
+				{Msg: "invalid EIP-55 checksum in mixed-case address", Lines: []int{2}},
+			},
+		},
+		{
+			name: "unexpected duplicates",
+			list: "1\n2\n1\n",
+			want: []Problem{
+				{Msg: `1 duplicated value(s), e.g. "1" ×2; set "duplicates" in the manifest if intentional`},
+			},
+		},
+		{
+			name: "declared duplicates",
+			list: "1\n2\n1\n",
+			draw: Draw{Duplicates: true},
+		},
+		{
+			name: "duplicates with distinct dedupe",
+			list: "1\n2\n1\n",
+			draw: Draw{Dedupe: DedupeDistinct},
+		},
+		{
+			name: "capitalisations",
+			list: lower + "\n" + checksum + "\n",
+			want: []Problem{
+				{Msg: "1 address(es) with differently capitalised entries, treated as different values by the draw, e.g. " + checksum},
+			},
+		},
+		{
+			name: "mixed addresses and IDs",
+			list: lower + "\n42\n",
+			want: []Problem{
+				{Msg: "mixed content: 1 address(es), 1 numeric ID(s)"},
+			},
+		},
+		{
+			name: "weights ignored",
+			list: lower + ",3\n" + otherAddr + "\n",
+			draw: Draw{Weighted: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, LintList([]byte(tt.list), tt.draw)); diff != "" {
+				t.Errorf("LintList(%q, %+v) diff (-want +got):\n%s", tt.list, tt.draw, diff)
+			}
+		})
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	tests := []struct {
+		p    Problem
+		want string
+	}{
+		{Problem{Msg: "x"}, "x"},
+		{Problem{Msg: "x", Lines: []int{4}}, "line 4: x"},
+		{Problem{Msg: "x", Lines: []int{4, 5, 6}}, "lines 4, 5, 6: x"},
+		{Problem{Msg: "x", Lines: []int{4, 5, 6, 7, 8}}, "lines 4, 5, 6 and 2 more: x"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("%+v.String() got %q; want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalList(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		draw Draw
+		want string
+	}{
+		{
+			name: "whitespace",
+			in:   "\n b\r\n\na \n\tc",
+			want: "b\na\nc\n",
+		},
+		{
+			name: "weighted",
+			in:   " a, 2 \r\nb\n",
+			draw: Draw{Weighted: true},
+			want: "a, 2\nb\n",
+		},
+		{
+			name: "already canonical",
+			in:   "a\nb\n",
+			want: "a\nb\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CanonicalList([]byte(tt.in))
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("CanonicalList(%q) diff (-want +got):\n%s", tt.in, diff)
+			}
+			if ps := LintList(got, tt.draw); len(ps) > 0 {
+				t.Errorf("LintList(CanonicalList(%q)) got problems %v", tt.in, ps)
+			}
+			if same, err := SameDraws([]byte(tt.in), got, tt.draw); err != nil || !same {
+				t.Errorf("SameDraws(%q, CanonicalList(…)) got %t, err %v; want true, nil", tt.in, same, err)
+			}
+		})
+	}
+
+	for _, pair := range [][2]string{
+		{"a\nb\n", "a\nB\n"},
+		{"a\nb\n", "a\nb\nb\n"},
+	} {
+		if same, err := SameDraws([]byte(pair[0]), []byte(pair[1]), Draw{}); err != nil || same {
+			t.Errorf("SameDraws(%q, %q) got %t, err %v; want false, nil", pair[0], pair[1], same, err)
+		}
+	}
+	if same, err := SameDraws([]byte("a,2\n"), []byte(strings.Repeat("a\n", 2)), Draw{Weighted: true}); err != nil || !same {
+		t.Errorf("SameDraws() of weighted list and its expansion got %t, err %v; want true, nil", same, err)
+	}
+}
@@ -0,0 +1,288 @@
+// Package raffle defines the declarative manifests that describe each raffle
+// in this repository, and the logic to conduct the draws that they describe.
+package raffle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/proofxyz/raffles/entropy"
+)
+
+// ManifestFile is the name of the file, present in each raffle folder, that
+// holds the folder's Manifest.
+const ManifestFile = "raffle.json"
+
+// A Manifest describes all draws performed in a single raffle folder. All draws
+// share the folder's entropy file.
+//
+// A manifest either has independent Draws or prize Tiers, but not both.
+type Manifest struct {
+	// Dir is the folder containing the manifest, as found by Find() or Load().
+	Dir   string `json:"-"`
+	Draws []Draw `json:"draws,omitempty"`
+	// Tiers are drawn in priority order, i.e. the order in which they appear.
+	Tiers []Tier `json:"tiers,omitempty"`
+	// Exclusive excludes winners of each tier from all later tiers. Excluded
+	// addresses are skipped, in the shuffled order of the later tier, rather
+	// than removed from its list before shuffling. Tiers that have no winners
+	// in common are therefore drawn identically, regardless of exclusivity.
+	Exclusive bool `json:"exclusive,omitempty"`
+
+	// series, if non-nil, is the Series of which the manifest's folder is the
+	// named member.
+	series *Series
+	member string
+}
+
+// A Tier is a named prize tier of a tiered manifest. Repetitions are not
+// supported; instead, use separate tiers.
+type Tier struct {
+	Name string `json:"name"`
+	Draw
+}
+
+// A Draw describes a single list from which winners are drawn.
+type Draw struct {
+	// Description is an optional, human-readable note.
+	Description string `json:"description,omitempty"`
+	// List is the path of the list to draw from, relative to the manifest.
+	List string `json:"list"`
+	// Winners is the number of winners to draw; 0 outputs the entire shuffled
+	// list.
+	Winners int `json:"winners"`
+	// Fold is the number of times that the entropy is folded before drawing;
+	// see entropy.Fold(). It is only retained for historical raffles, and new
+	// ones SHOULD use Derive instead.
+	Fold int `json:"fold,omitempty"`
+	// Derive, if non-empty, is the label with which a sub-seed is derived from
+	// the entropy; see entropy.Derive(). It is mutually exclusive with Fold.
+	Derive string `json:"derive,omitempty"`
+	// Repetitions, if greater than 1, repeats the draw with a different seed
+	// for each repetition. With Fold, the entropy is folded an additional time
+	// for each repetition; i.e. Fold, Fold+1, Fold+2, etc. With Derive, the
+	// label of the i-th repetition (1-indexed) is "<Derive>/<i>".
+	Repetitions int `json:"repetitions,omitempty"`
+	// Dedupe determines the treatment of duplicate lines in the list.
+	Dedupe Dedupe `json:"dedupe,omitempty"`
+	// Weighted indicates that lines in the list MAY be of the form
+	// `value,weight`, equivalent to value being repeated weight times; see
+	// shuffle.SortedWeighted().
+	Weighted bool `json:"weighted,omitempty"`
+	// Duplicates indicates that duplicate lines in the list are intentional,
+	// e.g. one entry per token held, and therefore not reported by LintList().
+	Duplicates bool `json:"duplicates,omitempty"`
+}
+
+// Dedupe determines how duplicate lines in a list are treated.
+type Dedupe string
+
+const (
+	// DedupeNone treats every line as a separate entry, which may therefore
+	// win more than once.
+	DedupeNone Dedupe = ""
+	// DedupeDistinct selects the first Winners distinct lines of the full
+	// shuffle. Duplicate lines (or weights) therefore act as extra entries,
+	// but each value can only win once; see shuffle.DrawDistinct().
+	DedupeDistinct Dedupe = "distinct"
+)
+
+// Find returns all manifests in the tree rooted at root, in lexical order of
+// their paths, except that the members of a Series are in the order of the
+// series.
+func Find(root string) ([]*Manifest, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == ManifestFile {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filepath.WalkDir(%q): %v", root, err)
+	}
+	sort.Strings(paths)
+
+	var ms []*Manifest
+	for _, p := range paths {
+		m, err := Load(p)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	orderSeries(ms)
+	return ms, nil
+}
+
+// orderSeries reorders the members of each Series, amongst the positions that
+// they already occupy, into the order of the series. Each member MAY require
+// the committed results of those before it.
+func orderSeries(ms []*Manifest) {
+	positions := make(map[string][]int)
+	for i, m := range ms {
+		if m.series != nil {
+			positions[m.series.Dir] = append(positions[m.series.Dir], i)
+		}
+	}
+	for _, pos := range positions {
+		members := make([]*Manifest, len(pos))
+		for j, i := range pos {
+			members[j] = ms[i]
+		}
+		sort.SliceStable(members, func(a, b int) bool {
+			return members[a].series.index(members[a].member) < members[b].series.index(members[b].member)
+		})
+		for j, i := range pos {
+			ms[i] = members[j]
+		}
+	}
+}
+
+// Dirs returns all raffle folders in the tree rooted at root, in lexical order.
+// A folder is considered to be a raffle folder if it contains either an entropy
+// file or a manifest.
+func Dirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		for _, name := range []string{entropy.FileName, ManifestFile} {
+			if fi, err := os.Stat(filepath.Join(path, name)); err == nil && fi.Mode().IsRegular() {
+				dirs = append(dirs, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filepath.WalkDir(%q): %v", root, err)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// Load reads and validates the manifest at path.
+func Load(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+
+	m := &Manifest{Dir: filepath.Dir(path)}
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Decode(%q): %v", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %q: %v", path, err)
+	}
+
+	abs, err := filepath.Abs(m.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Abs(%q): %v", m.Dir, err)
+	}
+	switch s, err := LoadSeries(filepath.Join(m.Dir, "..")); {
+	case errors.Is(err, errNoSeries):
+	case err != nil:
+		return nil, err
+	case s.index(filepath.Base(abs)) != -1:
+		m.series, m.member = s, filepath.Base(abs)
+	}
+	return m, nil
+}
+
+// validate performs sense checks on the manifest.
+func (m *Manifest) validate() error {
+	switch {
+	case len(m.Draws) == 0 && len(m.Tiers) == 0:
+		return errors.New("no draws or tiers")
+	case len(m.Draws) > 0 && len(m.Tiers) > 0:
+		return errors.New("draws and tiers are mutually exclusive")
+	case m.Exclusive && len(m.Tiers) == 0:
+		return errors.New("exclusive without tiers")
+	}
+
+	for i, d := range m.Draws {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("draws[%d]: %v", i, err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, t := range m.Tiers {
+		if t.Name == "" {
+			return fmt.Errorf("tiers[%d]: empty name", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("tiers[%d]: duplicate name %q", i, t.Name)
+		}
+		names[t.Name] = true
+
+		if t.Repetitions > 0 {
+			return fmt.Errorf("tiers[%d]: repetitions unsupported", i)
+		}
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("tiers[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// validate performs sense checks on the Draw.
+func (d Draw) validate() error {
+	if d.List == "" {
+		return errors.New("empty list")
+	}
+	if filepath.IsAbs(d.List) {
+		return fmt.Errorf("list %q must be relative to the manifest", d.List)
+	}
+	if d.Winners < 0 || d.Fold < 0 || d.Repetitions < 0 {
+		return errors.New("negative winners, fold, or repetitions")
+	}
+	if d.Fold > 0 && d.Derive != "" {
+		return errors.New("fold and derive are mutually exclusive")
+	}
+	switch d.Dedupe {
+	case DedupeNone:
+	case DedupeDistinct:
+		if d.Winners == 0 {
+			return fmt.Errorf("%q dedupe requires non-zero winners", d.Dedupe)
+		}
+	default:
+		return fmt.Errorf("unknown dedupe %q", d.Dedupe)
+	}
+	return nil
+}
+
+// ListPath returns the path to the Draw's list, relative to the working
+// directory.
+func (m *Manifest) ListPath(d Draw) string {
+	return filepath.Join(m.Dir, filepath.FromSlash(d.List))
+}
+
+// EntropyPath returns the path to the manifest's entropy file.
+func (m *Manifest) EntropyPath() string {
+	return filepath.Join(m.Dir, entropy.FileName)
+}
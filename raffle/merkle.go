@@ -0,0 +1,98 @@
+package raffle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/proofxyz/raffles/merkle"
+)
+
+// MerkleRootsPath returns the path to the manifest's merkle.RootsFile.
+func (m *Manifest) MerkleRootsPath() string {
+	return filepath.Join(m.Dir, merkle.RootsFile)
+}
+
+// MerkleTrees returns a Merkle tree over the entries of each of the manifest's
+// lists, in order of first use, and the Roots of the trees as recorded in the
+// merkle.RootsFile.
+func (m *Manifest) MerkleTrees() (*merkle.Roots, []*merkle.Tree, error) {
+	roots := &merkle.Roots{Scheme: merkle.Scheme}
+	var trees []*merkle.Tree
+	done := make(map[string]bool)
+	for _, d := range m.AllDraws() {
+		if done[d.List] {
+			continue
+		}
+		done[d.List] = true
+
+		lines, err := m.ReadList(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		t, err := merkle.New(merkle.Entries(lines))
+		if err != nil {
+			return nil, nil, fmt.Errorf("list %q: %v", d.List, err)
+		}
+		roots.Lists = append(roots.Lists, merkle.ListRoot{
+			List:    d.List,
+			Root:    t.Root(),
+			Entries: len(t.Entries()),
+			Lines:   uint64(len(lines)),
+		})
+		trees = append(trees, t)
+	}
+	return roots, trees, nil
+}
+
+// merkleRootsJSON returns the contents of the merkle.RootsFile for the roots.
+func merkleRootsJSON(roots *merkle.Roots) ([]byte, error) {
+	buf, err := json.MarshalIndent(roots, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent(%T): %v", roots, err)
+	}
+	return append(buf, '\n'), nil
+}
+
+// WriteMerkleRoots writes the roots to the manifest's merkle.RootsFile.
+func (m *Manifest) WriteMerkleRoots(roots *merkle.Roots) error {
+	buf, err := merkleRootsJSON(roots)
+	if err != nil {
+		return err
+	}
+	path := m.MerkleRootsPath()
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return fmt.Errorf("os.WriteFile(%q): %v", path, err)
+	}
+	return nil
+}
+
+// CheckMerkleRoots confirms that the manifest's merkle.RootsFile, if any, is
+// identical to that which would be written for its current lists, returning
+// an error wrapping ErrResultsMismatch if not. Only large lists have roots, so
+// a missing file isn't an error.
+func (m *Manifest) CheckMerkleRoots() error {
+	path := m.MerkleRootsPath()
+	got, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("os.ReadFile(%q): %v", path, err)
+	}
+
+	roots, _, err := m.MerkleTrees()
+	if err != nil {
+		return err
+	}
+	want, err := merkleRootsJSON(roots)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: %q", ErrResultsMismatch, path)
+	}
+	return nil
+}
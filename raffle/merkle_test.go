@@ -0,0 +1,73 @@
+package raffle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/proofxyz/raffles/merkle"
+)
+
+func TestMerkleRoots(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFile: `{
+			"tiers": [
+				{"name": "gold", "list": "participants", "winners": 1},
+				{"name": "silver", "list": "participants", "winners": 1},
+				{"name": "bronze", "list": "others", "winners": 1}
+			]
+		}`,
+		"participants": "a\nb\nb\n",
+		"others":       "c\n",
+	})
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+
+	if err := m.CheckMerkleRoots(); err != nil {
+		t.Errorf("%T.CheckMerkleRoots() without %s error %v", m, merkle.RootsFile, err)
+	}
+
+	roots, trees, err := m.MerkleTrees()
+	if err != nil {
+		t.Fatalf("%T.MerkleTrees() error %v", m, err)
+	}
+	if got, want := len(roots.Lists), 2; got != want || len(trees) != want {
+		t.Fatalf("%T.MerkleTrees() got %d roots and %d trees; want %d of each", m, got, len(trees), want)
+	}
+	if got, want := roots.Lists[0], (merkle.ListRoot{List: "participants", Root: trees[0].Root(), Entries: 2, Lines: 3}); got != want {
+		t.Errorf("%T.MerkleTrees() first root got %+v; want %+v", m, got, want)
+	}
+
+	if err := m.WriteMerkleRoots(roots); err != nil {
+		t.Fatalf("%T.WriteMerkleRoots() error %v", m, err)
+	}
+	if err := m.CheckMerkleRoots(); err != nil {
+		t.Errorf("%T.CheckMerkleRoots() after writing error %v", m, err)
+	}
+
+	path := filepath.Join(dir, "others")
+	if err := os.WriteFile(path, []byte("d\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile(%q) error %v", path, err)
+	}
+	if err := m.CheckMerkleRoots(); !errors.Is(err, ErrResultsMismatch) {
+		t.Errorf("%T.CheckMerkleRoots() after changing list got err %v; want %v", m, err, ErrResultsMismatch)
+	}
+}
+
+// TestRepositoryMerkleRoots confirms that the committed Merkle roots of all
+// raffles in this repository match those of their lists.
+func TestRepositoryMerkleRoots(t *testing.T) {
+	manifests, err := Find("..")
+	if err != nil {
+		t.Fatalf("Find() error %v", err)
+	}
+
+	for _, m := range manifests {
+		if err := m.CheckMerkleRoots(); err != nil {
+			t.Errorf("%T.CheckMerkleRoots() error %v; run `go run ./cmd/merkle <folder>` after confirming that the change is intended", m, err)
+		}
+	}
+}
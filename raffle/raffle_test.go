@@ -0,0 +1,350 @@
+package raffle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/proofxyz/raffles/entropy"
+)
+
+// writeFiles creates a temporary directory containing the files, keyed by path
+// relative to the directory, and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("os.MkdirAll(%q) error %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("os.WriteFile(%q) error %v", path, err)
+		}
+	}
+	return dir
+}
+
+const (
+	testHash    = "0xf51e982a1322f2f0b054bda01e38918efeffcfcd6d0436b88d9a0d2bc826634f"
+	testEntropy = "# We will use the hash of block 1 as a random seed\n\n" + testHash + "\n"
+)
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{
+			name:     "no draws",
+			manifest: `{"draws": []}`,
+		},
+		{
+			name:     "unknown field",
+			manifest: `{"draws": [{"list": "participants", "winners": 1, "shuffle": true}]}`,
+		},
+		{
+			name:     "empty list",
+			manifest: `{"draws": [{"winners": 1}]}`,
+		},
+		{
+			name:     "absolute list",
+			manifest: `{"draws": [{"list": "/etc/passwd", "winners": 1}]}`,
+		},
+		{
+			name:     "negative winners",
+			manifest: `{"draws": [{"list": "participants", "winners": -1}]}`,
+		},
+		{
+			name:     "unknown dedupe",
+			manifest: `{"draws": [{"list": "participants", "winners": 1, "dedupe": "unique"}]}`,
+		},
+		{
+			name:     "fold and derive",
+			manifest: `{"draws": [{"list": "participants", "winners": 1, "fold": 1, "derive": "x"}]}`,
+		},
+		{
+			name:     "draws and tiers",
+			manifest: `{"draws": [{"list": "participants", "winners": 1}], "tiers": [{"name": "gold", "list": "participants", "winners": 1}]}`,
+		},
+		{
+			name:     "exclusive without tiers",
+			manifest: `{"draws": [{"list": "participants", "winners": 1}], "exclusive": true}`,
+		},
+		{
+			name:     "unnamed tier",
+			manifest: `{"tiers": [{"list": "participants", "winners": 1}]}`,
+		},
+		{
+			name:     "duplicate tier",
+			manifest: `{"tiers": [{"name": "gold", "list": "participants", "winners": 1}, {"name": "gold", "list": "participants", "winners": 1}]}`,
+		},
+		{
+			name:     "tier repetitions",
+			manifest: `{"tiers": [{"name": "gold", "list": "participants", "winners": 1, "repetitions": 2}]}`,
+		},
+		{
+			name:     "invalid tier",
+			manifest: `{"tiers": [{"name": "gold", "winners": 1}]}`,
+		},
+		{
+			name:     "distinct without winners",
+			manifest: `{"draws": [{"list": "participants", "dedupe": "distinct"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{ManifestFile: tt.manifest})
+			if _, err := Load(filepath.Join(dir, ManifestFile)); err == nil {
+				t.Errorf("Load(%s) got nil error; want error", tt.manifest)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFile: `{
+			"draws": [
+				{"list": "participants", "winners": 2},
+				{"list": "participants", "winners": 2, "fold": 1, "repetitions": 3},
+				{"list": "duplicates", "winners": 2, "dedupe": "distinct"},
+				{"list": "weights", "winners": 2, "dedupe": "distinct", "weighted": true}
+			]
+		}`,
+		"entropy":      testEntropy,
+		"participants": "a\nb\nc\nd\ne\nf\n",
+		"duplicates":   "a\na\na\na\na\na\nb\n",
+		"weights":      "a,6\nb,1\n",
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	results, err := m.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() error %v", m, err)
+	}
+
+	if got, want := len(results), 6; got != want {
+		t.Fatalf("%T.Run() got %d results; want %d", m, got, want)
+	}
+	for i, r := range results[:4] {
+		if got, want := len(r.Winners), 2; got != want {
+			t.Errorf("%T.Run()[%d] got %d winners; want %d", m, i, got, want)
+		}
+	}
+	for i, want := range []int{0, 1, 2, 3} {
+		if got := results[i].Fold; got != want {
+			t.Errorf("%T.Run()[%d].Fold = %d; want %d", m, i, got, want)
+		}
+	}
+	if got, want := results[0].Entropy, "f51e982a1322f2f0b054bda01e38918efeffcfcd6d0436b88d9a0d2bc826634f"; got != want {
+		t.Errorf("%T.Run()[0].Entropy = %q; want %q", m, got, want)
+	}
+
+	for _, r := range results[4:] {
+		got := append([]string{}, r.Winners...)
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"a", "b"}, got); diff != "" {
+			t.Errorf("%T.Run() distinct winners from %q diff (-want +got, both sorted):\n%s", m, r.List, diff)
+		}
+	}
+	if diff := cmp.Diff(results[4].Winners, results[5].Winners); diff != "" {
+		t.Errorf("%T.Run() weighted list differs from equivalent repeated list; diff (-repeated +weighted):\n%s", m, diff)
+	}
+}
+
+func TestRunDerive(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFile: `{
+			"draws": [
+				{"list": "participants", "winners": 3, "derive": "mint"},
+				{"list": "participants", "winners": 3, "derive": "mint", "repetitions": 2}
+			]
+		}`,
+		"entropy":      testEntropy,
+		"participants": "a\nb\nc\nd\ne\nf\n",
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	results, err := m.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() error %v", m, err)
+	}
+
+	seed := common.HexToHash(testHash)
+	for i, label := range []string{"mint", "mint/1", "mint/2"} {
+		r := results[i]
+		if r.Label != label || r.Fold != 0 {
+			t.Errorf("%T.Run()[%d] got label %q and fold %d; want %q and 0", m, i, r.Label, r.Fold, label)
+		}
+		if got, want := r.Entropy, strings.TrimPrefix(entropy.Derive(seed, label).Hex(), "0x"); got != want {
+			t.Errorf("%T.Run()[%d].Entropy = %q; want %q", m, i, got, want)
+		}
+	}
+}
+
+func TestRunTiers(t *testing.T) {
+	var lower, upper []string
+	for i := 1; i <= 6; i++ {
+		a := common.BigToAddress(big.NewInt(int64(i * 0xabcdef))).Hex()
+		lower = append(lower, strings.ToLower(a))
+		upper = append(upper, "0x"+strings.ToUpper(a[2:]))
+	}
+
+	const tiers = `[
+		{"name": "gold", "list": "participants", "winners": 2},
+		{"name": "silver", "list": "participants", "winners": 2},
+		{"name": "bronze", "list": "participants", "winners": 0},
+		{"name": "checksum", "list": "upper", "winners": 1}
+	]`
+	files := map[string]string{
+		"entropy":      testEntropy,
+		"participants": strings.Join(lower, "\n"),
+		"upper":        strings.Join(upper, "\n"),
+	}
+
+	run := func(t *testing.T, exclusive bool) []Result {
+		t.Helper()
+		files[ManifestFile] = fmt.Sprintf(`{"tiers": %s, "exclusive": %t}`, tiers, exclusive)
+		m, err := Load(filepath.Join(writeFiles(t, files), ManifestFile))
+		if err != nil {
+			t.Fatalf("Load() error %v", err)
+		}
+		results, err := m.Run()
+		if err != nil {
+			t.Fatalf("%T.Run() error %v", m, err)
+		}
+		for i, name := range []string{"gold", "silver", "bronze", "checksum"} {
+			if got := results[i].Tier; got != name {
+				t.Errorf("%T.Run()[%d].Tier = %q; want %q", m, i, got, name)
+			}
+		}
+		return results
+	}
+
+	independent := run(t, false)
+	// As every tier shares the same list and seed, the full shuffle is the
+	// bronze tier when drawn independently.
+	shuffled := independent[2].Winners
+
+	want := map[bool][][]string{
+		false: {shuffled[:2], shuffled[:2], shuffled},
+		true:  {shuffled[:2], shuffled[2:4], shuffled[4:]},
+	}
+	for _, exclusive := range []bool{false, true} {
+		results := run(t, exclusive)
+		var got [][]string
+		for _, r := range results[:3] {
+			got = append(got, r.Winners)
+		}
+		if diff := cmp.Diff(want[exclusive], got); diff != "" {
+			t.Errorf("exclusive=%t; winners diff (-want +got):\n%s", exclusive, diff)
+		}
+
+		if n := len(results[3].Winners); exclusive == (n != 0) {
+			t.Errorf("exclusive=%t; got %d winners of tier with differently capitalised addresses", exclusive, n)
+		}
+	}
+}
+
+func TestRunNoEntropy(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		ManifestFile:   `{"draws": [{"list": "participants", "winners": 1}]}`,
+		"entropy":      "# We will use the hash of block 1 as a random seed\n",
+		"participants": "a\nb\n",
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if _, err := m.Run(); !errors.Is(err, ErrNoEntropy) {
+		t.Errorf("%T.Run() with unset entropy got err %v; want %v", m, err, ErrNoEntropy)
+	}
+}
+
+// TestRepositoryManifests confirms that the manifests of all raffles in this
+// repository reproduce the historical draws locked in by the shuffle package's
+// golden files, which were originally described by raffle.sh.
+func TestRepositoryManifests(t *testing.T) {
+	const root = ".."
+	manifests, err := Find(root)
+	if err != nil {
+		t.Fatalf("Find(%q) error %v", root, err)
+	}
+	if len(manifests) == 0 {
+		t.Fatalf("Find(%q) found no manifests", root)
+	}
+
+	for _, m := range manifests {
+		results, err := m.Run()
+		if err != nil {
+			t.Errorf("%T.Run() in %q error %v", m, m.Dir, err)
+			continue
+		}
+
+		for _, r := range results {
+			rel, err := filepath.Rel(root, r.List)
+			if err != nil {
+				t.Fatalf("filepath.Rel(%q, %q) error %v", root, r.List, err)
+			}
+			name := filepath.ToSlash(rel)
+			if r.Fold > 0 {
+				name = fmt.Sprintf("%s/fold-%d", name, r.Fold)
+			}
+
+			golden := filepath.Join(root, "shuffle", "testdata", "golden", strings.ReplaceAll(name, "/", "_"))
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Errorf("os.ReadFile(%q) error %v", golden, err)
+				continue
+			}
+			got := strings.Join(r.Winners, "\n") + "\n"
+			if diff := cmp.Diff(string(want), got); diff != "" {
+				t.Errorf("%s: winners differ from golden file %q (-want +got):\n%s", name, golden, diff)
+			}
+		}
+	}
+}
+
+func TestDirs(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"a/entropy":                  testEntropy,
+		"a/participants":             "x\n",
+		"b/c/" + ManifestFile:        `{"draws": [{"list": "participants", "winners": 1}]}`,
+		"d/participants":             "x\n",
+		"entropy/file.go":            "package entropy\n", // a directory named entropy isn't a raffle
+		".git/objects/entropy":       "",
+		"e/nested/entropy/README.md": "",
+	})
+
+	got, err := Dirs(root)
+	if err != nil {
+		t.Fatalf("Dirs() error %v", err)
+	}
+	for i := range got {
+		got[i], err = filepath.Rel(root, got[i])
+		if err != nil {
+			t.Fatalf("filepath.Rel() error %v", err)
+		}
+	}
+
+	want := []string{"a", filepath.Join("b", "c")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Dirs() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,320 @@
+package raffle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/proofxyz/raffles/entropy"
+)
+
+// ResultsFile and ResultsCSVFile are the names of the files, written next to a
+// manifest, that record the Results of all of its draws.
+const (
+	ResultsFile    = "results.json"
+	ResultsCSVFile = "results.csv"
+)
+
+// Results are the machine-readable record of all draws of a manifest.
+type Results struct {
+	// Block is the number of the block whose hash is the entropy.
+	Block     uint64      `json:"block"`
+	Entropy   common.Hash `json:"entropy"`
+	Exclusive bool        `json:"exclusive,omitempty"`
+	// Draws are in the order returned by Manifest.Run().
+	Draws []DrawResult `json:"draws"`
+}
+
+// A DrawResult records the winners of a single repetition of a Draw, or of a
+// Tier.
+type DrawResult struct {
+	Tier        string `json:"tier,omitempty"`
+	Description string `json:"description,omitempty"`
+	// List is the path of the list, relative to the manifest.
+	List string `json:"list"`
+	// ListSHA256 is the SHA-256 of the contents of the list file, as computed
+	// by `sha256sum` and recorded in a CommitmentFile. It pins the exact bytes,
+	// so any change to the file invalidates the results, even one that doesn't
+	// alter the draw, such as rewriting it with CanonicalList();
+	// CheckResults() names such lists.
+	ListSHA256 common.Hash `json:"listSHA256"`
+	// Repetition is the 1-indexed repetition, only present if the Draw is
+	// repeated.
+	Repetition int    `json:"repetition,omitempty"`
+	Fold       int    `json:"fold"`
+	Label      string `json:"label,omitempty"`
+	// Entropy is the seed of the shuffle, after folding or derivation.
+	Entropy common.Hash `json:"entropy"`
+	Winners []Winner    `json:"winners"`
+}
+
+// A Winner is a selected line of a list, and its 1-indexed position in the
+// order of selection.
+type Winner struct {
+	Position int    `json:"position"`
+	Value    string `json:"value"`
+}
+
+// Results converts the results of Run() into a single record, reading the
+// manifest's entropy and lists to record their provenance.
+func (m *Manifest) Results(results []Result) (*Results, error) {
+	e, err := entropy.ParseFile(m.EntropyPath())
+	if err != nil {
+		return nil, err
+	}
+	if e.Pending() {
+		return nil, ErrNoEntropy
+	}
+
+	rs := &Results{
+		Block:     e.Block,
+		Entropy:   *e.Hash,
+		Exclusive: m.Exclusive,
+		Draws:     []DrawResult{},
+	}
+	hashes := make(map[string]common.Hash)
+	for _, r := range results {
+		h, ok := hashes[r.List]
+		if !ok {
+			var err error
+			if h, err = listSHA256(r.List); err != nil {
+				return nil, err
+			}
+			hashes[r.List] = h
+		}
+
+		dr := DrawResult{
+			Tier:        r.Tier,
+			Description: r.Draw.Description,
+			List:        r.Draw.List,
+			ListSHA256:  h,
+			Fold:        r.Fold,
+			Label:       r.Label,
+			Entropy:     common.HexToHash(r.Entropy),
+			Winners:     []Winner{},
+		}
+		if r.Draw.Repetitions > 1 {
+			dr.Repetition = r.Repetition
+		}
+		for i, w := range r.Winners {
+			dr.Winners = append(dr.Winners, Winner{Position: i + 1, Value: w})
+		}
+		rs.Draws = append(rs.Draws, dr)
+	}
+	return rs, nil
+}
+
+// listSHA256 returns the hash of the list recorded as DrawResult.ListSHA256.
+func listSHA256(path string) (common.Hash, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("os.ReadFile(%q): %v", path, err)
+	}
+	return sha256.Sum256(buf), nil
+}
+
+// JSON returns the indented JSON encoding of the Results, with a trailing
+// newline, as written to ResultsFile.
+func (rs *Results) JSON() ([]byte, error) {
+	buf, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent(%T): %v", rs, err)
+	}
+	return append(buf, '\n'), nil
+}
+
+// CSV returns the Results as CSV, with one row per winner, as written to
+// ResultsCSVFile. Draw-level fields are repeated in every row.
+func (rs *Results) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"tier", "list", "list_sha256", "repetition", "fold", "label", "entropy", "position", "winner"})
+	for _, d := range rs.Draws {
+		for _, win := range d.Winners {
+			w.Write([]string{
+				d.Tier,
+				d.List,
+				d.ListSHA256.Hex(),
+				strconv.Itoa(d.Repetition),
+				strconv.Itoa(d.Fold),
+				d.Label,
+				d.Entropy.Hex(),
+				strconv.Itoa(win.Position),
+				win.Value,
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("%T.Flush(): %v", w, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resultsFiles returns the contents of ResultsFile and ResultsCSVFile, keyed
+// by path.
+func (m *Manifest) resultsFiles(rs *Results) (map[string][]byte, error) {
+	j, err := rs.JSON()
+	if err != nil {
+		return nil, err
+	}
+	c, err := rs.CSV()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		filepath.Join(m.Dir, ResultsFile):    j,
+		filepath.Join(m.Dir, ResultsCSVFile): c,
+	}, nil
+}
+
+// WriteResults writes the Results to the manifest's ResultsFile and
+// ResultsCSVFile.
+func (m *Manifest) WriteResults(rs *Results) error {
+	files, err := m.resultsFiles(rs)
+	if err != nil {
+		return err
+	}
+	for path, buf := range files {
+		if err := os.WriteFile(path, buf, 0644); err != nil {
+			return fmt.Errorf("os.WriteFile(%q): %v", path, err)
+		}
+	}
+	return nil
+}
+
+// ErrResultsMismatch is returned by CheckResults() if the committed results
+// differ from those recomputed.
+var ErrResultsMismatch = errors.New("committed results don't match recomputation")
+
+// CommittedResults returns the manifest's ResultsFile without conducting the
+// draws, returning an error wrapping ErrResultsMismatch if it is missing or
+// stale; i.e. if its entropy or any of its lists differ from the manifest's, or
+// it doesn't record the manifest's draws. The winners themselves are only
+// confirmed by CheckResults(). If the manifest's entropy is yet to be set,
+// CommittedResults returns ErrNoEntropy.
+func (m *Manifest) CommittedResults() (*Results, error) {
+	e, err := entropy.ParseFile(m.EntropyPath())
+	if err != nil {
+		return nil, err
+	}
+	if e.Pending() {
+		return nil, ErrNoEntropy
+	}
+
+	path := filepath.Join(m.Dir, ResultsFile)
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %q missing", ErrResultsMismatch, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile(%q): %v", path, err)
+	}
+	rs := new(Results)
+	if err := json.Unmarshal(buf, rs); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(%q, %T): %v", path, rs, err)
+	}
+
+	if rs.Block != e.Block || rs.Entropy != *e.Hash {
+		return nil, fmt.Errorf("%w: %q stale; entropy of block %d %v; want block %d %v", ErrResultsMismatch, path, rs.Block, rs.Entropy, e.Block, *e.Hash)
+	}
+	want := m.drawnLists()
+	if len(rs.Draws) != len(want) {
+		return nil, fmt.Errorf("%w: %q stale; %d draws; want %d", ErrResultsMismatch, path, len(rs.Draws), len(want))
+	}
+	for i, d := range rs.Draws {
+		if d.Tier != want[i].Tier || d.List != want[i].List {
+			return nil, fmt.Errorf("%w: %q stale; draw %d of tier %q, list %q; want tier %q, list %q", ErrResultsMismatch, path, i, d.Tier, d.List, want[i].Tier, want[i].List)
+		}
+		h, err := listSHA256(m.ListPath(Draw{List: d.List}))
+		if err != nil {
+			return nil, err
+		}
+		if d.ListSHA256 != h {
+			return nil, fmt.Errorf("%w: %q stale; list %q has SHA-256 %v; committed %v", ErrResultsMismatch, path, d.List, h, d.ListSHA256)
+		}
+	}
+	return rs, nil
+}
+
+// drawnLists returns the Tier and List of every DrawResult that Results()
+// records for the manifest, in order.
+func (m *Manifest) drawnLists() []DrawResult {
+	var drs []DrawResult
+	for _, t := range m.Tiers {
+		drs = append(drs, DrawResult{Tier: t.Name, List: t.List})
+	}
+	for _, d := range m.Draws {
+		reps := d.Repetitions
+		if reps == 0 {
+			reps = 1
+		}
+		for i := 0; i < reps; i++ {
+			drs = append(drs, DrawResult{List: d.List})
+		}
+	}
+	return drs
+}
+
+// CheckResults confirms that the manifest's ResultsFile and ResultsCSVFile are
+// identical to those that WriteResults() would write for rs, returning an
+// error wrapping ErrResultsMismatch if not, or if either file is missing. The
+// error names every list whose contents have changed since the results were
+// committed.
+func (m *Manifest) CheckResults(rs *Results) error {
+	files, err := m.resultsFiles(rs)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{ResultsFile, ResultsCSVFile} {
+		path := filepath.Join(m.Dir, name)
+		got, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %q missing", ErrResultsMismatch, path)
+		}
+		if err != nil {
+			return fmt.Errorf("os.ReadFile(%q): %v", path, err)
+		}
+		if !bytes.Equal(got, files[path]) {
+			return fmt.Errorf("%w: %q%s", ErrResultsMismatch, path, changedLists(got, rs))
+		}
+	}
+	return nil
+}
+
+// changedLists returns a description of the lists with different hashes in
+// the committed ResultsFile, buf, and rs, or an empty string if there are none
+// or buf can't be parsed.
+func changedLists(buf []byte, rs *Results) string {
+	var committed Results
+	if err := json.Unmarshal(buf, &committed); err != nil {
+		return ""
+	}
+	was := make(map[string]common.Hash)
+	for _, d := range committed.Draws {
+		was[d.List] = d.ListSHA256
+	}
+
+	var changed []string
+	seen := make(map[string]bool)
+	for _, d := range rs.Draws {
+		h, ok := was[d.List]
+		if !ok || h == d.ListSHA256 || seen[d.List] {
+			continue
+		}
+		seen[d.List] = true
+		changed = append(changed, fmt.Sprintf("%q has SHA-256 %v; committed %v", d.List, d.ListSHA256, h))
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+	return "; list(s) changed since the results were committed, even if drawn identically: " + strings.Join(changed, ", ")
+}
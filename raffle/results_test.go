@@ -0,0 +1,152 @@
+package raffle
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/proofxyz/raffles/entropy"
+)
+
+func TestResults(t *testing.T) {
+	const participants = "a\nb\nc\n"
+	dir := writeFiles(t, map[string]string{
+		ManifestFile: `{
+			"tiers": [
+				{"name": "gold", "list": "participants", "winners": 1},
+				{"name": "silver", "list": "participants", "winners": 2, "derive": "silver"}
+			],
+			"exclusive": true
+		}`,
+		"entropy":      testEntropy,
+		"participants": participants,
+	})
+
+	m, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	results, err := m.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() error %v", m, err)
+	}
+	rs, err := m.Results(results)
+	if err != nil {
+		t.Fatalf("%T.Results() error %v", m, err)
+	}
+
+	if err := m.CheckResults(rs); !errors.Is(err, ErrResultsMismatch) {
+		t.Errorf("%T.CheckResults() before writing got err %v; want %v", m, err, ErrResultsMismatch)
+	}
+	if err := m.WriteResults(rs); err != nil {
+		t.Fatalf("%T.WriteResults() error %v", m, err)
+	}
+	if err := m.CheckResults(rs); err != nil {
+		t.Errorf("%T.CheckResults() after writing error %v", m, err)
+	}
+
+	buf, err := os.ReadFile(filepath.Join(dir, ResultsFile))
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", ResultsFile, err)
+	}
+	var got Results
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error %v", buf, err)
+	}
+
+	seed := common.HexToHash(testHash)
+	listHash := common.Hash(sha256.Sum256([]byte(participants)))
+	want := Results{
+		Block:     1,
+		Entropy:   seed,
+		Exclusive: true,
+		Draws: []DrawResult{
+			{
+				Tier:       "gold",
+				List:       "participants",
+				ListSHA256: listHash,
+				Entropy:    seed,
+				Winners: []Winner{
+					{Position: 1, Value: results[0].Winners[0]},
+				},
+			},
+			{
+				Tier:       "silver",
+				List:       "participants",
+				ListSHA256: listHash,
+				Label:      "silver",
+				Entropy:    entropy.Derive(seed, "silver"),
+				Winners: []Winner{
+					{Position: 1, Value: results[1].Winners[0]},
+					{Position: 2, Value: results[1].Winners[1]},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%s diff (-want +got):\n%s", ResultsFile, diff)
+	}
+
+	csv, err := os.ReadFile(filepath.Join(dir, ResultsCSVFile))
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", ResultsCSVFile, err)
+	}
+	wantCSV := "tier,list,list_sha256,repetition,fold,label,entropy,position,winner\n" +
+		"gold,participants," + listHash.Hex() + ",0,0,," + seed.Hex() + ",1," + results[0].Winners[0] + "\n" +
+		"silver,participants," + listHash.Hex() + ",0,0,silver," + entropy.Derive(seed, "silver").Hex() + ",1," + results[1].Winners[0] + "\n" +
+		"silver,participants," + listHash.Hex() + ",0,0,silver," + entropy.Derive(seed, "silver").Hex() + ",2," + results[1].Winners[1] + "\n"
+	if diff := cmp.Diff(wantCSV, string(csv)); diff != "" {
+		t.Errorf("%s diff (-want +got):\n%s", ResultsCSVFile, diff)
+	}
+
+	// Any change to the list, even if it doesn't alter the winners, MUST be
+	// detected via its hash.
+	if err := os.WriteFile(filepath.Join(dir, "participants"), []byte(participants+"\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error %v", err)
+	}
+	changed, err := m.Results(results)
+	if err != nil {
+		t.Fatalf("%T.Results() error %v", m, err)
+	}
+	err = m.CheckResults(changed)
+	if !errors.Is(err, ErrResultsMismatch) {
+		t.Fatalf("%T.CheckResults() after modifying list got err %v; want %v", m, err, ErrResultsMismatch)
+	}
+	if !strings.Contains(err.Error(), `"participants" has SHA-256`) {
+		t.Errorf("%T.CheckResults() after modifying list got err %v; want naming the list", m, err)
+	}
+}
+
+// TestRepositoryResults confirms that the committed results of all raffles in
+// this repository match a fresh recomputation.
+func TestRepositoryResults(t *testing.T) {
+	manifests, err := Find("..")
+	if err != nil {
+		t.Fatalf("Find() error %v", err)
+	}
+
+	for _, m := range manifests {
+		results, err := m.Run()
+		if errors.Is(err, ErrNoEntropy) {
+			continue
+		}
+		if err != nil {
+			t.Errorf("%T.Run() in %q error %v", m, m.Dir, err)
+			continue
+		}
+		rs, err := m.Results(results)
+		if err != nil {
+			t.Errorf("%T.Results() in %q error %v", m, m.Dir, err)
+			continue
+		}
+		if err := m.CheckResults(rs); err != nil {
+			t.Errorf("%T.CheckResults() error %v; run `go run ./cmd/raffle -write_results` after confirming that the change is intended", m, err)
+		}
+	}
+}
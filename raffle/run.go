@@ -0,0 +1,245 @@
+package raffle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/proofxyz/raffles/entropy"
+	"github.com/proofxyz/raffles/shuffle"
+)
+
+// ErrNoEntropy is returned by Manifest.Run() if the entropy file has yet to be
+// populated with the committed block hash.
+var ErrNoEntropy = errors.New("no entropy set")
+
+// A Result is the outcome of a single repetition of a Draw, or of a Tier.
+type Result struct {
+	Draw Draw
+	// Tier is the name of the Tier, if any.
+	Tier string
+	// Repetition is the 1-indexed repetition of the Draw.
+	Repetition int
+	// List is the path of the list, relative to the working directory.
+	List string
+	// Fold is the number of times that the entropy was folded.
+	Fold int
+	// Label is the label with which the entropy was derived, if any.
+	Label string
+	// Entropy is the hex-encoded entropy, after folding or derivation, without
+	// a 0x prefix.
+	Entropy string
+	Winners []string
+}
+
+// Run conducts all draws described by the manifest, returning one Result for
+// each repetition of each Draw, or for each Tier, in order. If the manifest's
+// entropy is yet to be set, Run returns ErrNoEntropy.
+//
+// If the manifest's folder has a CommitmentFile, Run refuses to draw from lists
+// that no longer match it.
+//
+// If the manifest's folder is a member of a Series, the winners of earlier
+// members are treated according to the series' PreviousWinners, which MAY
+// require their committed results. If any of their entropy is yet to be set,
+// Run returns an error wrapping ErrNoEntropy.
+func (m *Manifest) Run() ([]Result, error) {
+	if m.series == nil {
+		return m.run(prior{})
+	}
+	p, err := m.series.priorFor(m.member)
+	if err != nil {
+		return nil, err
+	}
+	return m.run(p)
+}
+
+// prior describes the treatment of the winners of earlier draws, keyed by
+// exclusionKey().
+type prior struct {
+	excluded     map[string]bool
+	downweighted map[string]bool
+	// downweight is the number of times that all entries other than those
+	// downweighted are repeated.
+	downweight int
+}
+
+// run is equivalent to Run() but with an explicit treatment of earlier
+// winners.
+func (m *Manifest) run(p prior) ([]Result, error) {
+	e, err := entropy.ParseFile(m.EntropyPath())
+	if err != nil {
+		return nil, err
+	}
+	if e.Pending() {
+		return nil, ErrNoEntropy
+	}
+	switch err := m.VerifyCommitment(); {
+	case errors.Is(err, ErrNoCommitment):
+	case err != nil:
+		return nil, fmt.Errorf("refusing to draw: %w", err)
+	}
+	seed := *e.Hash
+
+	if len(m.Tiers) > 0 {
+		return m.runTiers(seed, p)
+	}
+
+	var results []Result
+	for _, d := range m.Draws {
+		reps := d.Repetitions
+		if reps == 0 {
+			reps = 1
+		}
+		for i := 0; i < reps; i++ {
+			fold, label := d.Fold+i, ""
+			if d.Derive != "" {
+				fold, label = 0, d.Derive
+				if reps > 1 {
+					label = fmt.Sprintf("%s/%d", d.Derive, i+1)
+				}
+			}
+
+			r, err := m.draw(d, seed, fold, label, p)
+			if err != nil {
+				return nil, err
+			}
+			r.Repetition = i + 1
+			results = append(results, *r)
+		}
+	}
+	return results, nil
+}
+
+// runTiers draws each Tier in priority order, additionally excluding the
+// winners of earlier tiers if the manifest is Exclusive.
+func (m *Manifest) runTiers(seed common.Hash, p prior) ([]Result, error) {
+	excluded := make(map[string]bool)
+	for k := range p.excluded {
+		excluded[k] = true
+	}
+	p.excluded = excluded
+
+	var results []Result
+	for _, t := range m.Tiers {
+		r, err := m.draw(t.Draw, seed, t.Fold, t.Derive, p)
+		if err != nil {
+			return nil, fmt.Errorf("tier %q: %v", t.Name, err)
+		}
+		r.Tier, r.Repetition = t.Name, 1
+		results = append(results, *r)
+
+		if !m.Exclusive {
+			continue
+		}
+		for _, w := range r.Winners {
+			excluded[exclusionKey(w)] = true
+		}
+	}
+	return results, nil
+}
+
+// exclusionKey returns the key by which a winner is excluded from later tiers.
+// Addresses are compared regardless of their checksum capitalisation.
+func exclusionKey(line string) string {
+	if common.IsHexAddress(line) {
+		return common.HexToAddress(line).Hex()
+	}
+	return line
+}
+
+// draw performs a single repetition of the Draw, with the seed either folded or
+// derived with the label, and earlier winners treated according to p.
+func (m *Manifest) draw(d Draw, seed common.Hash, fold int, label string, p prior) (*Result, error) {
+	if label != "" {
+		seed = entropy.Derive(seed, label)
+	} else {
+		seed = entropy.Fold(seed, fold)
+	}
+
+	r := &Result{
+		Draw:    d,
+		List:    m.ListPath(d),
+		Fold:    fold,
+		Label:   label,
+		Entropy: strings.TrimPrefix(seed.Hex(), "0x"),
+	}
+
+	buf, err := shuffle.DecodeHex(r.Entropy)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := m.ReadList(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.downweighted) > 0 {
+		lines = downweight(lines, p.downweighted, p.downweight)
+	}
+	if err := shuffle.Shuffle(buf, lines); err != nil {
+		return nil, fmt.Errorf("shuffle.Shuffle(%q, %q): %v", r.Entropy, r.List, err)
+	}
+
+	distinct := d.Dedupe == DedupeDistinct || len(p.downweighted) > 0
+	switch {
+	case len(p.excluded) > 0:
+		seen := make(map[string]bool)
+		lines = shuffle.SelectFunc(lines, d.Winners, func(l []byte) bool {
+			if p.excluded[exclusionKey(string(l))] || seen[string(l)] {
+				return false
+			}
+			if distinct {
+				seen[string(l)] = true
+			}
+			return true
+		})
+	case distinct:
+		lines = shuffle.SelectDistinct(lines, d.Winners)
+	default:
+		lines = shuffle.Select(lines, d.Winners)
+	}
+	for _, l := range lines {
+		r.Winners = append(r.Winners, string(l))
+	}
+	return r, nil
+}
+
+// downweight returns the sorted lines with every line repeated n times, except
+// for those for which exclusionKey() is in downweighted.
+func downweight(lines [][]byte, downweighted map[string]bool, n int) [][]byte {
+	var out [][]byte
+	for _, l := range lines {
+		reps := n
+		if downweighted[exclusionKey(string(l))] {
+			reps = 1
+		}
+		for i := 0; i < reps; i++ {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// ReadList returns the sorted entries of the Draw's list, as drawn; see
+// shuffle.SortedNonEmpty() and shuffle.SortedWeighted().
+func (m *Manifest) ReadList(d Draw) ([][]byte, error) {
+	path := m.ListPath(d)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	read := shuffle.SortedNonEmpty
+	if d.Weighted {
+		read = shuffle.SortedWeighted
+	}
+	lines, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %v", path, err)
+	}
+	return lines, nil
+}
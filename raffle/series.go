@@ -0,0 +1,278 @@
+package raffle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SeriesFile is the name of the file, in the parent folder of a series of
+// raffle folders, that holds the Series.
+const SeriesFile = "series.json"
+
+// A Series is an ordered set of raffle folders, e.g. weekly raffles, in which
+// the draws of each member MAY take into account the winners of all earlier
+// members.
+type Series struct {
+	// Dir is the folder containing the series file.
+	Dir         string `json:"-"`
+	Description string `json:"description,omitempty"`
+	// Members are the names of the raffle folders, each a direct child of Dir
+	// with its own manifest, in the order in which they were drawn.
+	Members []string `json:"members"`
+	// PreviousWinners determines the treatment of the winners of earlier
+	// members.
+	PreviousWinners PreviousWinners `json:"previousWinners,omitempty"`
+	// Downweight is the factor by which the entries of previous winners are
+	// less likely to be drawn than all other entries. It is required by, and
+	// only valid with, PreviousWinnersDownweighted.
+	Downweight int `json:"downweight,omitempty"`
+}
+
+// PreviousWinners determines how the winners of earlier members of a Series
+// are treated in later draws. Previous winners are identified by their lines,
+// with addresses compared regardless of their checksum capitalisation.
+type PreviousWinners string
+
+const (
+	// PreviousWinnersEligible draws each member independently.
+	PreviousWinnersEligible PreviousWinners = ""
+	// PreviousWinnersExcluded skips previous winners, in the shuffled order, in
+	// the same manner as exclusive tiers; see Manifest.Exclusive.
+	PreviousWinnersExcluded PreviousWinners = "exclude"
+	// PreviousWinnersDownweighted repeats every entry of a list Downweight
+	// times, except for those of previous winners, before shuffling. Winners
+	// are then selected as with DedupeDistinct, as the repetition would
+	// otherwise increase the chance of an address winning more than once.
+	PreviousWinnersDownweighted PreviousWinners = "downweight"
+)
+
+// FindSeries returns all series in the tree rooted at root, in lexical order
+// of their paths.
+func FindSeries(root string) ([]*Series, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == SeriesFile {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filepath.WalkDir(%q): %v", root, err)
+	}
+	sort.Strings(dirs)
+
+	var ss []*Series
+	for _, d := range dirs {
+		s, err := LoadSeries(d)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// errNoSeries is returned by LoadSeries() if dir doesn't contain a SeriesFile.
+var errNoSeries = errors.New("no series")
+
+// LoadSeries reads and validates the series file in dir.
+func LoadSeries(dir string) (*Series, error) {
+	path := filepath.Join(dir, SeriesFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w in %q", errNoSeries, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%q): %v", path, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+
+	s := &Series{Dir: dir}
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Decode(%q): %v", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid series %q: %v", path, err)
+	}
+	return s, nil
+}
+
+// validate performs sense checks on the series.
+func (s *Series) validate() error {
+	if len(s.Members) == 0 {
+		return errors.New("no members")
+	}
+	seen := make(map[string]bool)
+	for i, m := range s.Members {
+		if m == "" || m == "." || m == ".." || strings.ContainsAny(m, `/\`) {
+			return fmt.Errorf("members[%d]: %q is not the name of a child folder", i, m)
+		}
+		if seen[m] {
+			return fmt.Errorf("members[%d]: duplicate %q", i, m)
+		}
+		seen[m] = true
+	}
+
+	switch s.PreviousWinners {
+	case PreviousWinnersEligible, PreviousWinnersExcluded:
+		if s.Downweight != 0 {
+			return fmt.Errorf("downweight with %q previous winners", s.PreviousWinners)
+		}
+	case PreviousWinnersDownweighted:
+		if s.Downweight < 2 {
+			return fmt.Errorf("downweight %d must be at least 2", s.Downweight)
+		}
+	default:
+		return fmt.Errorf("unknown previous winners %q", s.PreviousWinners)
+	}
+	return nil
+}
+
+// index returns the index of the member in Members, or -1 if it isn't a
+// member.
+func (s *Series) index(member string) int {
+	for i, m := range s.Members {
+		if m == member {
+			return i
+		}
+	}
+	return -1
+}
+
+// manifest loads the manifest of the i-th member.
+func (s *Series) manifest(i int) (*Manifest, error) {
+	return Load(filepath.Join(s.Dir, s.Members[i], ManifestFile))
+}
+
+// A SeriesResult is the outcome of all draws of a single member of a Series.
+type SeriesResult struct {
+	Member  string
+	Results []Result
+}
+
+// Run conducts the draws of all members of the series, in order. Members whose
+// entropy is yet to be set, and all that follow them, are omitted.
+//
+// Unlike Manifest.Run(), the winners of earlier members are those drawn by Run
+// itself rather than their committed results.
+func (s *Series) Run() ([]SeriesResult, error) {
+	previous := make(map[string]bool)
+
+	var out []SeriesResult
+	for i := range s.Members {
+		m, err := s.manifest(i)
+		if err != nil {
+			return nil, err
+		}
+		results, err := m.run(s.prior(previous))
+		if errors.Is(err, ErrNoEntropy) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("series member %q: %v", s.Members[i], err)
+		}
+		out = append(out, SeriesResult{
+			Member:  s.Members[i],
+			Results: results,
+		})
+
+		for _, r := range results {
+			for _, w := range r.Winners {
+				previous[exclusionKey(w)] = true
+			}
+		}
+	}
+	return out, nil
+}
+
+// prior returns the treatment of the previous winners under the series'
+// policy.
+func (s *Series) prior(previous map[string]bool) prior {
+	switch s.PreviousWinners {
+	case PreviousWinnersExcluded:
+		return prior{excluded: previous}
+	case PreviousWinnersDownweighted:
+		return prior{downweighted: previous, downweight: s.Downweight}
+	default:
+		return prior{}
+	}
+}
+
+// priorFor returns the treatment of the winners of all members of the series
+// preceding the member. Their winners are read from their committed results,
+// which MUST NOT be missing or stale; see Manifest.CommittedResults(). Each
+// member's results are themselves confirmed by Manifest.CheckResults(), so
+// later members needn't redraw all of those before them.
+func (s *Series) priorFor(member string) (prior, error) {
+	i := s.index(member)
+	if i == -1 {
+		return prior{}, fmt.Errorf("%q not a member of series %q", member, s.Dir)
+	}
+	if s.PreviousWinners == PreviousWinnersEligible {
+		return prior{}, nil
+	}
+
+	previous := make(map[string]bool)
+	for j := 0; j < i; j++ {
+		m, err := s.manifest(j)
+		if err != nil {
+			return prior{}, err
+		}
+		rs, err := m.CommittedResults()
+		if errors.Is(err, ErrNoEntropy) {
+			return prior{}, fmt.Errorf("%w for earlier series member %q", ErrNoEntropy, s.Members[j])
+		}
+		if err != nil {
+			return prior{}, fmt.Errorf("earlier series member %q: %w", s.Members[j], err)
+		}
+		for _, d := range rs.Draws {
+			for _, w := range d.Winners {
+				previous[exclusionKey(w.Value)] = true
+			}
+		}
+	}
+	return s.prior(previous), nil
+}
+
+// RepeatWinners returns, for every winner of more than one member of the
+// series, the members that they won, in order. Winners are identified as
+// for PreviousWinners.
+func RepeatWinners(results []SeriesResult) map[string][]string {
+	won := make(map[string][]string)
+	for _, sr := range results {
+		inMember := make(map[string]bool)
+		for _, r := range sr.Results {
+			for _, w := range r.Winners {
+				k := exclusionKey(w)
+				if inMember[k] {
+					continue
+				}
+				inMember[k] = true
+				won[k] = append(won[k], sr.Member)
+			}
+		}
+	}
+
+	repeats := make(map[string][]string)
+	for w, members := range won {
+		if len(members) > 1 {
+			repeats[w] = members
+		}
+	}
+	return repeats
+}
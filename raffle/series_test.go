@@ -0,0 +1,317 @@
+package raffle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeSeries creates a series of identical members, each drawing one winner
+// from the same list with the same entropy, and returns its directory.
+func writeSeries(t *testing.T, members []string, policy string) string {
+	t.Helper()
+	buf, err := json.Marshal(members)
+	if err != nil {
+		t.Fatalf("json.Marshal(%q) error %v", members, err)
+	}
+	files := map[string]string{
+		SeriesFile: fmt.Sprintf(`{"members": %s, %s}`, buf, policy),
+	}
+	for _, m := range members {
+		files[m+"/"+ManifestFile] = `{"draws": [{"list": "participants", "winners": 1}]}`
+		files[m+"/entropy"] = testEntropy
+		files[m+"/participants"] = "a\nb\nc\nd\n"
+	}
+	return writeFiles(t, files)
+}
+
+// writeMemberResults runs the member's manifest and writes its results, as
+// required by later members of a series.
+func writeMemberResults(t *testing.T, dir, member string) (*Manifest, []Result) {
+	t.Helper()
+	m, err := Load(filepath.Join(dir, member, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load(%q) error %v", member, err)
+	}
+	results, err := m.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() of %q error %v", m, member, err)
+	}
+	rs, err := m.Results(results)
+	if err != nil {
+		t.Fatalf("%T.Results() of %q error %v", m, member, err)
+	}
+	if err := m.WriteResults(rs); err != nil {
+		t.Fatalf("%T.WriteResults() of %q error %v", m, member, err)
+	}
+	return m, results
+}
+
+func TestSeries(t *testing.T) {
+	members := []string{"week-1", "week-2", "week-3"}
+
+	tests := []struct {
+		name   string
+		policy string
+		// wantDistinct indicates that all members MUST have different winners.
+		wantDistinct bool
+		// wantSame indicates that all members MUST have the same winner, as
+		// they have identical lists and entropy.
+		wantSame bool
+	}{
+		{
+			name:     "eligible",
+			policy:   `"previousWinners": ""`,
+			wantSame: true,
+		},
+		{
+			name:         "exclude",
+			policy:       `"previousWinners": "exclude"`,
+			wantDistinct: true,
+		},
+		{
+			name:   "downweight",
+			policy: `"previousWinners": "downweight", "downweight": 2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSeries(t, members, tt.policy)
+			s, err := LoadSeries(dir)
+			if err != nil {
+				t.Fatalf("LoadSeries() error %v", err)
+			}
+			results, err := s.Run()
+			if err != nil {
+				t.Fatalf("%T.Run() error %v", s, err)
+			}
+			if got, want := len(results), len(members); got != want {
+				t.Fatalf("%T.Run() got %d results; want %d", s, got, want)
+			}
+
+			won := make(map[string][]string)
+			for i, sr := range results {
+				if sr.Member != members[i] {
+					t.Errorf("%T.Run()[%d].Member = %q; want %q", s, i, sr.Member, members[i])
+				}
+
+				// Running a single member, from the committed results of those
+				// before it, MUST be equivalent to running it as part of the
+				// series.
+				m, single := writeMemberResults(t, dir, sr.Member)
+				if diff := cmp.Diff(sr.Results, single); diff != "" {
+					t.Errorf("%T.Run() of %q differs from %T.Run(); diff (-series +manifest):\n%s", m, sr.Member, s, diff)
+				}
+
+				w := sr.Results[0].Winners[0]
+				won[w] = append(won[w], sr.Member)
+			}
+
+			if tt.wantDistinct && len(won) != len(members) {
+				t.Errorf("%T.Run() got winners %v; want all distinct", s, won)
+			}
+			if tt.wantSame {
+				if len(won) != 1 {
+					t.Errorf("%T.Run() got winners %v; want all the same", s, won)
+				}
+				if diff := cmp.Diff(won, RepeatWinners(results)); diff != "" {
+					t.Errorf("RepeatWinners() diff (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSeriesPendingEntropy(t *testing.T) {
+	members := []string{"week-1", "week-2", "week-3"}
+	dir := writeSeries(t, members, `"previousWinners": "exclude"`)
+	pending := filepath.Join(dir, "week-2", "entropy")
+	if err := os.WriteFile(pending, []byte("# We will use the hash of block 2 as a random seed\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile(%q) error %v", pending, err)
+	}
+
+	writeMemberResults(t, dir, "week-1")
+
+	s, err := LoadSeries(dir)
+	if err != nil {
+		t.Fatalf("LoadSeries() error %v", err)
+	}
+	results, err := s.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() error %v", s, err)
+	}
+	if got, want := len(results), 1; got != want {
+		t.Errorf("%T.Run() with pending second member got %d results; want %d", s, got, want)
+	}
+
+	m, err := Load(filepath.Join(dir, "week-3", ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	if _, err := m.Run(); !errors.Is(err, ErrNoEntropy) {
+		t.Errorf("%T.Run() after pending series member got err %v; want %v", m, err, ErrNoEntropy)
+	}
+}
+
+func TestSeriesCommittedResults(t *testing.T) {
+	members := []string{"week-1", "week-2"}
+
+	tests := []struct {
+		name string
+		// modify is called after the results of week-1 are written.
+		modify  func(t *testing.T, dir string)
+		wantErr error
+	}{
+		{
+			name: "missing",
+			modify: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "week-1", ResultsFile)); err != nil {
+					t.Fatalf("os.Remove() error %v", err)
+				}
+			},
+			wantErr: ErrResultsMismatch,
+		},
+		{
+			name: "list changed",
+			modify: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "week-1", "participants"), []byte("a\nb\nc\nd\ne\n"), 0644); err != nil {
+					t.Fatalf("os.WriteFile() error %v", err)
+				}
+			},
+			wantErr: ErrResultsMismatch,
+		},
+		{
+			name: "entropy changed",
+			modify: func(t *testing.T, dir string) {
+				e := "# We will use the hash of block 2 as a random seed\n\n" + testHash + "\n"
+				if err := os.WriteFile(filepath.Join(dir, "week-1", "entropy"), []byte(e), 0644); err != nil {
+					t.Fatalf("os.WriteFile() error %v", err)
+				}
+			},
+			wantErr: ErrResultsMismatch,
+		},
+		{
+			name:   "unchanged",
+			modify: func(*testing.T, string) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSeries(t, members, `"previousWinners": "exclude"`)
+			_, first := writeMemberResults(t, dir, "week-1")
+			tt.modify(t, dir)
+
+			m, err := Load(filepath.Join(dir, "week-2", ManifestFile))
+			if err != nil {
+				t.Fatalf("Load() error %v", err)
+			}
+			results, err := m.Run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%T.Run() got err %v; want %v", m, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, prev := results[0].Winners[0], first[0].Winners[0]; got == prev {
+				t.Errorf("%T.Run() got winner %q of earlier member", m, got)
+			}
+		})
+	}
+
+	// The winners of earlier members are read from their committed results,
+	// not redrawn.
+	dir := writeSeries(t, members, `"previousWinners": "exclude"`)
+	m1, _ := writeMemberResults(t, dir, "week-1")
+	m2, err := Load(filepath.Join(dir, "week-2", ManifestFile))
+	if err != nil {
+		t.Fatalf("Load() error %v", err)
+	}
+	results, err := m2.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() error %v", m2, err)
+	}
+	won := results[0].Winners[0]
+
+	rs, err := m1.CommittedResults()
+	if err != nil {
+		t.Fatalf("%T.CommittedResults() error %v", m1, err)
+	}
+	rs.Draws[0].Winners[0].Value = won
+	if err := m1.WriteResults(rs); err != nil {
+		t.Fatalf("%T.WriteResults() error %v", m1, err)
+	}
+	results, err = m2.Run()
+	if err != nil {
+		t.Fatalf("%T.Run() after changing committed winner error %v", m2, err)
+	}
+	if got := results[0].Winners[0]; got == won {
+		t.Errorf("%T.Run() got winner %q; want exclusion of committed winner of earlier member", m2, got)
+	}
+}
+
+func TestDownweight(t *testing.T) {
+	lines := [][]byte{[]byte("a"), []byte("b"), []byte("b"), []byte("c")}
+	got := downweight(lines, map[string]bool{"b": true}, 3)
+
+	var want [][]byte
+	for _, l := range "aaabbccc" {
+		want = append(want, []byte(string(l)))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("downweight(%q, {b}, 3) diff (-want +got):\n%s", lines, diff)
+	}
+}
+
+func TestLoadSeriesErrors(t *testing.T) {
+	for _, series := range []string{
+		`{"members": []}`,
+		`{"members": ["a", "a"]}`,
+		`{"members": ["a/b"]}`,
+		`{"members": [".."]}`,
+		`{"members": ["a"], "previousWinners": "ignore"}`,
+		`{"members": ["a"], "previousWinners": "downweight"}`,
+		`{"members": ["a"], "previousWinners": "downweight", "downweight": 1}`,
+		`{"members": ["a"], "previousWinners": "exclude", "downweight": 2}`,
+		`{"members": ["a"], "unknown": true}`,
+	} {
+		dir := writeFiles(t, map[string]string{SeriesFile: series})
+		if _, err := LoadSeries(dir); err == nil {
+			t.Errorf("LoadSeries(%s) got nil error; want error", series)
+		}
+	}
+}
+
+func TestFindOrdersSeries(t *testing.T) {
+	dir := writeSeries(t, []string{"week-b", "week-a"}, `"previousWinners": "exclude"`)
+	for name, content := range map[string]string{
+		"other/" + ManifestFile: `{"draws": [{"list": "participants", "winners": 1}]}`,
+		"other/participants":    "a\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("os.MkdirAll() error %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("os.WriteFile() error %v", err)
+		}
+	}
+
+	ms, err := Find(dir)
+	if err != nil {
+		t.Fatalf("Find() error %v", err)
+	}
+	var got []string
+	for _, m := range ms {
+		got = append(got, filepath.Base(m.Dir))
+	}
+	if diff := cmp.Diff([]string{"other", "week-b", "week-a"}, got); diff != "" {
+		t.Errorf("Find() folders diff (-want +got):\n%s", diff)
+	}
+}
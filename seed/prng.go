@@ -0,0 +1,109 @@
+package seed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/rand"
+)
+
+// A PRNG identifies the algorithm with which a seed is expanded into a stream
+// of pseudo-random numbers. Values are versioned so that the output for a
+// given seed never changes; an improved algorithm MUST be added as a new PRNG.
+type PRNG string
+
+const (
+	// Legacy folds the seed into an int64 with Fold() and uses it to seed
+	// math/rand's original generator, vendored as NewLegacySource(). It only
+	// has 64 bits of entropy and is retained for the reproduction of
+	// historical results.
+	Legacy PRNG = "legacy"
+	// HMACSHA256 is a counter-mode stream keyed with all 256 bits of the seed;
+	// see NewStream().
+	HMACSHA256 PRNG = "hmac-sha256/v1"
+
+	// Default is the PRNG that SHOULD be used for new allocations.
+	Default = HMACSHA256
+)
+
+// ParsePRNG returns the PRNG named by s, or an error if it is unknown.
+func ParsePRNG(s string) (PRNG, error) {
+	switch p := PRNG(s); p {
+	case Legacy, HMACSHA256:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown PRNG %q; must be %q or %q", s, Legacy, HMACSHA256)
+	}
+}
+
+// NewSource returns a new rand.Source keyed with the seed. Sources returned
+// by successive calls with the same seed produce identical output. NewSource
+// panics if p isn't a known PRNG; see ParsePRNG().
+func (p PRNG) NewSource(key [32]byte) rand.Source {
+	switch p {
+	case Legacy:
+		return NewLegacySource(fold(key))
+	case HMACSHA256:
+		return NewStream(key)
+	default:
+		panic(fmt.Sprintf("unknown %T %q", p, p))
+	}
+}
+
+// StreamDomain separates the output of a Stream from any other use of the same
+// seed.
+const StreamDomain = "proofxyz/raffles/seed.Stream/v1"
+
+// A Stream is a rand.Source64 whose output is the concatenation of blocks
+//
+//	HMAC-SHA256(key = seed, message = StreamDomain || 0x00 || uint64_be(i))
+//
+// for i = 0, 1, 2, …, each block being read as four big-endian uint64s.
+type Stream struct {
+	mac     hash.Hash
+	counter uint64
+	block   []byte
+	next    int // index of the first unused byte of block
+}
+
+var _ rand.Source64 = (*Stream)(nil)
+
+// NewStream returns a Stream keyed with the seed.
+func NewStream(key [32]byte) *Stream {
+	return &Stream{
+		mac: hmac.New(sha256.New, key[:]),
+	}
+}
+
+// Uint64 returns the next 64 bits of the stream.
+func (s *Stream) Uint64() uint64 {
+	if s.next == len(s.block) {
+		var ctr [8]byte
+		binary.BigEndian.PutUint64(ctr[:], s.counter)
+		s.counter++
+
+		s.mac.Reset()
+		s.mac.Write([]byte(StreamDomain))
+		s.mac.Write([]byte{0})
+		s.mac.Write(ctr[:])
+		s.block = s.mac.Sum(s.block[:0])
+		s.next = 0
+	}
+	u := binary.BigEndian.Uint64(s.block[s.next:])
+	s.next += 8
+	return u
+}
+
+// Int63 returns the high 63 bits of the next Uint64(), as a non-negative
+// int64.
+func (s *Stream) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed panics as a Stream is keyed with 256 bits, which can't be replaced by
+// an int64.
+func (s *Stream) Seed(int64) {
+	panic(fmt.Sprintf("%T.Seed() unsupported; use NewStream()", s))
+}
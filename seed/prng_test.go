@@ -0,0 +1,145 @@
+package seed
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		seed string
+		// Computed independently with Python's hmac module:
+		//
+		//	hmac.new(key, b'proofxyz/raffles/seed.Stream/v1\x00' + i.to_bytes(8, 'big'), hashlib.sha256)
+		blocks []string
+	}{
+		{
+			seed: "0x88ec393de1ce2661fa3a5034adde28f02a18529df47ea4285ad0d025265baa0e",
+			blocks: []string{
+				"5fe7d279291f7cbf94180bb381a10400b3f6b22d5a2c4582605e6919a969cc4f",
+				"b5634b3c8f26e96656798d1228f76db4035ba53ed4941fe6cc63a11cf3072455",
+			},
+		},
+		{
+			seed: "0",
+			blocks: []string{
+				"1d34ec50145bee26375bbf7537f594bc7e41c56e18859ac1b441ab59f399aff1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		key, err := Parse(tt.seed)
+		if err != nil {
+			t.Fatalf("Parse(%q) error %v", tt.seed, err)
+		}
+		s := NewStream(key)
+
+		for i, b := range tt.blocks {
+			block, err := hex.DecodeString(b)
+			if err != nil {
+				t.Fatalf("hex.DecodeString(%q) error %v", b, err)
+			}
+			for j := 0; j < len(block); j += 8 {
+				if got, want := s.Uint64(), binary.BigEndian.Uint64(block[j:]); got != want {
+					t.Errorf("NewStream(%q) block %d, word %d; got %#016x; want %#016x", tt.seed, i, j/8, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamInt63(t *testing.T) {
+	var key [32]byte
+	a, b := NewStream(key), NewStream(key)
+	for i := 0; i < 100; i++ {
+		if got, want := a.Int63(), int64(b.Uint64()>>1); got != want {
+			t.Fatalf("%T.Int63() call %d got %d; want %d", a, i, got, want)
+		}
+	}
+}
+
+func TestPRNGNewSource(t *testing.T) {
+	const seedHex = "0x709a2835af9b07919b6bf561d6e4e0c2c03e206e6eae64a1077ffad242fbc119"
+	key, err := Parse(seedHex)
+	if err != nil {
+		t.Fatalf("Parse(%q) error %v", seedHex, err)
+	}
+	folded, err := Fold(seedHex)
+	if err != nil {
+		t.Fatalf("Fold(%q) error %v", seedHex, err)
+	}
+
+	legacy := rand.New(Legacy.NewSource(key))
+	want := rand.New(NewLegacySource(folded))
+	for i := 0; i < 100; i++ {
+		if got, want := legacy.Int63(), want.Int63(); got != want {
+			t.Fatalf("%q source call %d got %d; want %d as from NewLegacySource(Fold())", Legacy, i, got, want)
+		}
+	}
+
+	// Successive sources with the same seed are identical.
+	x, y := rand.New(HMACSHA256.NewSource(key)), rand.New(HMACSHA256.NewSource(key))
+	for i := 0; i < 100; i++ {
+		if got, want := x.Intn(1000), y.Intn(1000); got != want {
+			t.Fatalf("%q sources diverged at call %d; got %d and %d", HMACSHA256, i, got, want)
+		}
+	}
+}
+
+func TestParsePRNG(t *testing.T) {
+	for _, p := range []PRNG{Legacy, HMACSHA256, Default} {
+		if got, err := ParsePRNG(string(p)); err != nil || got != p {
+			t.Errorf("ParsePRNG(%q) got %q, err %v; want %q, nil", p, got, err, p)
+		}
+	}
+	for _, s := range []string{"", "hmac-sha256", "chacha20"} {
+		if _, err := ParsePRNG(s); err == nil {
+			t.Errorf("ParsePRNG(%q) got nil error", s)
+		}
+	}
+}
+
+func TestLegacySourceGolden(t *testing.T) {
+	// The output of a rand.Rand over the Legacy source, as historically
+	// produced by rand.NewSource(). A change in any of these values, including
+	// those of the rand.Rand methods, would break the reproduction of
+	// historical results.
+	tests := []struct {
+		seed    int64
+		int63   int64
+		uint64  uint64
+		intn    int
+		float64 float64
+		shuffle []int
+	}{
+		{seed: 0, int63: 8717895732742165505, uint64: 2259404117704393152, intn: 353, float64: 0.05434383959970039, shuffle: []int{6, 9, 8, 7, 0, 5, 4, 1, 2, 3}},
+		{seed: 1, int63: 5577006791947779410, uint64: 8674665223082153551, intn: 847, float64: 0.4377141871869802, shuffle: []int{9, 5, 3, 2, 8, 7, 1, 0, 6, 4}},
+		{seed: 152818585473188023, int63: 4790993667404118816, uint64: 7374817020155658432, intn: 319, float64: 0.32207970121696633, shuffle: []int{3, 1, 9, 8, 5, 0, 6, 7, 2, 4}},
+		{seed: -1, int63: 3644962268338389676, uint64: 9773543399016688047, intn: 224, float64: 0.5622259697326123, shuffle: []int{8, 4, 0, 1, 3, 6, 9, 7, 2, 5}},
+	}
+
+	for _, tt := range tests {
+		rng := rand.New(NewLegacySource(tt.seed))
+		if got := rng.Int63(); got != tt.int63 {
+			t.Errorf("seed %d: Int63() got %d; want %d", tt.seed, got, tt.int63)
+		}
+		if got := rng.Uint64(); got != tt.uint64 {
+			t.Errorf("seed %d: Uint64() got %d; want %d", tt.seed, got, tt.uint64)
+		}
+		if got := rng.Intn(1000); got != tt.intn {
+			t.Errorf("seed %d: Intn(1000) got %d; want %d", tt.seed, got, tt.intn)
+		}
+		if got := rng.Float64(); got != tt.float64 {
+			t.Errorf("seed %d: Float64() got %v; want %v", tt.seed, got, tt.float64)
+		}
+		got := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		rng.Shuffle(len(got), func(i, j int) { got[i], got[j] = got[j], got[i] })
+		if !reflect.DeepEqual(got, tt.shuffle) {
+			t.Errorf("seed %d: Shuffle() got %v; want %v", tt.seed, got, tt.shuffle)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package seed converts hexadecimal seeds, typically block hashes, into sources
+// of randomness for math/rand.
+package seed
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Parse parses seedHex as a big-endian, 256-bit number. The 0x prefix is
+// optional, as are leading zeros, and at most 64 hex digits are accepted.
+func Parse(seedHex string) ([32]byte, error) {
+	var key [32]byte
+
+	digits := strings.TrimPrefix(seedHex, "0x")
+	if digits == "" {
+		return key, fmt.Errorf("empty hex seed %q", seedHex)
+	}
+	if len(digits) > 64 {
+		return key, fmt.Errorf("hex seed %q longer than 256 bits", seedHex)
+	}
+	digits = strings.Repeat("0", 64-len(digits)) + digits
+
+	if _, err := hex.Decode(key[:], []byte(digits)); err != nil {
+		return key, fmt.Errorf("hex.Decode(seed = %q): %v", seedHex, err)
+	}
+	return key, nil
+}
+
+// Fold treats seedHex as a uint256, returning the xor of its four 64-bit words,
+// reinterpreted as an int64 for use in a rand.Source. The seed is parsed as by
+// Parse().
+//
+// This unifies the implementations previously duplicated in the
+// diamond-exhibition and diamond-exhibition-reshuffling binaries, which
+// differed only in their handling of edge cases:
+//
+//   - diamond-exhibition rejected leading zeros (a limitation of
+//     uint256.FromHex()) and an empty seed; and
+//   - diamond-exhibition-reshuffling treated an empty seed as zero.
+//
+// Every seed accepted by both returns the same value as before, and Fold
+// accepts all seeds accepted by either, except for the empty string.
+func Fold(seedHex string) (int64, error) {
+	key, err := Parse(seedHex)
+	if err != nil {
+		return 0, err
+	}
+	return fold(key), nil
+}
+
+func fold(key [32]byte) int64 {
+	var folded uint64
+	for i := 0; i < len(key); i += 8 {
+		folded ^= binary.BigEndian.Uint64(key[i:])
+	}
+	return int64(folded)
+}
@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// xorWords is an independent reference implementation of Fold(), for seeds of
+// exactly 64 hex digits without a prefix.
+func xorWords(t *testing.T, digits string) int64 {
+	t.Helper()
+	buf, err := hex.DecodeString(digits)
+	if err != nil || len(buf) != 32 {
+		t.Fatalf("hex.DecodeString(%q) got %d bytes, err %v; want 32 bytes", digits, len(buf), err)
+	}
+	var u uint64
+	for i := 0; i < 32; i += 8 {
+		u ^= binary.BigEndian.Uint64(buf[i:])
+	}
+	return int64(u)
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name, seed string
+		want       int64
+	}{
+		{
+			// The committed seed of diamond-exhibition; see its README.
+			name: "diamond-exhibition block 17137900",
+			seed: "0x88ec393de1ce2661fa3a5034adde28f02a18529df47ea4285ad0d025265baa0e",
+			want: 152818585473188023,
+		},
+		{
+			// The hash of block 17237600, used by diamond-exhibition-reshuffling,
+			// isn't recorded in this repository so the binary's default seed
+			// and the following cases exercise the edge cases in which its
+			// original implementation differed from that of
+			// diamond-exhibition.
+			name: "diamond-exhibition-reshuffling default",
+			seed: fmt.Sprintf("%#x", [32]byte{}),
+			want: 0,
+		},
+		{
+			name: "diamond-exhibition default",
+			seed: "0",
+			want: 0,
+		},
+		{
+			name: "without prefix",
+			seed: "88ec393de1ce2661fa3a5034adde28f02a18529df47ea4285ad0d025265baa0e",
+			want: 152818585473188023,
+		},
+		{
+			name: "bonus-draw block 17243450",
+			seed: "0x709a2835af9b07919b6bf561d6e4e0c2c03e206e6eae64a1077ffad242fbc119",
+			want: 3220082428012544747,
+		},
+		{
+			name: "leading zeros",
+			seed: "0x00ff",
+			want: 255,
+		},
+		{
+			name: "mixed case",
+			seed: "0xABCdef",
+			want: 0xabcdef,
+		},
+		{
+			name: "all bits of a single word",
+			seed: "0xffffffffffffffff",
+			want: -1,
+		},
+		{
+			name: "sign bit",
+			seed: "0x8000000000000000",
+			want: -1 << 63,
+		},
+		{
+			name: "words cancel",
+			seed: "0x" + strings.Repeat("0123456789abcdef", 4),
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fold(tt.seed)
+			if err != nil {
+				t.Fatalf("Fold(%q) error %v", tt.seed, err)
+			}
+			if got != tt.want {
+				t.Errorf("Fold(%q) got %d; want %d", tt.seed, got, tt.want)
+			}
+
+			padded := strings.TrimPrefix(tt.seed, "0x")
+			padded = strings.Repeat("0", 64-len(padded)) + padded
+			if ref := xorWords(t, padded); got != ref {
+				t.Errorf("Fold(%q) got %d; reference implementation %d", tt.seed, got, ref)
+			}
+		})
+	}
+}
+
+func TestFoldErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"0x",
+		"0xg",
+		"0x1" + strings.Repeat("0", 64),
+	} {
+		if got, err := Fold(s); err == nil {
+			t.Errorf("Fold(%q) got %d, nil error; want error", s, got)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package shuffle
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/proofxyz/raffles/entropy"
+)
+
+var update = flag.Bool("update", false, "Regenerate golden files instead of comparing against them.")
+
+// historicalDraws mirrors the draws performed by raffle.sh at the root of the
+// repository, which were originally conducted with `ethier shuffle`.
+var historicalDraws = []struct {
+	list string
+	n    int
+	// fold is the number of times that the entropy is folded with sha256sum.
+	fold int
+	// distinct, if non-zero, is the number of distinct winners that were
+	// originally selected by piping the full shuffle through
+	// `awk '!seen[$0]++' | head -n <distinct>`.
+	distinct int
+}{
+	{list: "grails/season-02/holder-snapshot-grail-17", n: 1},
+	{list: "grails/season-02/holder-snapshot-grail-24", n: 1},
+	{list: "grails/season-02/holder-snapshot-grail-25", n: 1},
+	{list: "external/run-ed-moonbirds-miami/participants", n: 200},
+	{list: "grails/season-03/holder-snapshot-grail-8", n: 1},
+	{list: "external/defybirds-pwc/participants", n: 1535},
+	{list: "defybirds-unnested/participants", n: 185},
+	{list: "beeple-nyc/participants", n: 0},
+	{list: "growth/participants", n: 1000},
+	{list: "diamond-exhibition-reshuffling/bonus-draw/participants", distinct: 39},
+	{list: "toobins/jul-28/participants", n: 1},
+	{list: "grails/season-04/diamond-nested-mb-holders", distinct: 25},
+	{list: "toobins/aug-03/participants", n: 1},
+	{list: "grails/season-04-full-set/full-set-holders", n: 10},
+	{list: "grails/season-04-patron/diamond-exhibition-patrons", n: 3},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 1},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 2},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 3},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 4},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 5},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 6},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 7},
+	{list: "grails/season-04-staff-mints/projectIDs", n: 20, fold: 8},
+	{list: "grails/season-04-remaining-artist-choice/projectIDs", n: 20},
+	{list: "grails/season-04-remaining-giveaway-passes/projectIDs", n: 20, fold: 1},
+	{list: "grails/season-04-remaining-giveaway-passes/projectIDs", n: 20, fold: 2},
+	{list: "grails/season-04-remaining-giveaway-passes/projectIDs", n: 20, fold: 3},
+	{list: "toobins/aug-10/participants", n: 1},
+	{list: "toobins/aug-18/participants", n: 1},
+	{list: "toobins/aug-24/participants", n: 1},
+	{list: "toobins/sept-01/participants", n: 1},
+	{list: "toobins/sept-11/participants", n: 1},
+	{list: "toobins/sept-14/participants", n: 1},
+	{list: "toobins/sept-21/participants", n: 1},
+	{list: "toobins/sept-28/participants", n: 1},
+	{list: "toobins/oct-05/participants", n: 1},
+	{list: "toobins/oct-12/participants", n: 1},
+	{list: "toobins/oct-19/participants", n: 1},
+	{list: "toobins/oct-26/participants", n: 1},
+	{list: "toobins/oct-31/participants", n: 1},
+	{list: "toobins/nov-08/participants", n: 1},
+	{list: "toobins/nov-15/participants", n: 1},
+	{list: "toobins/dec-02/participants", n: 3},
+	{list: "toobins/dec-14/participants", n: 2},
+	{list: "toobins/dec-22/participants", n: 1},
+	{list: "toobins/dec-22-take-2/participants", n: 1},
+	{list: "toobins/dec-29/participants", n: 1},
+	{list: "grails/season-03-deafbeef-physical/cauldron", n: 1},
+	{list: "grails/season-03-deafbeef-physical/bronze", n: 1},
+	{list: "grails/season-03-deafbeef-physical/silverDiscount", n: 1},
+	{list: "grails/season-03-deafbeef-physical/silver", n: 1},
+	{list: "grails/season-03-deafbeef-physical/copperSwirl", n: 1},
+	{list: "grails/season-03-deafbeef-physical/copper3", n: 1},
+	{list: "grails/season-03-deafbeef-physical/gold", n: 1},
+	{list: "talons/season-01/ledger-epiphany/participants", n: 1},
+	{list: "every-30-days/participants", n: 3},
+	{list: "lunar-society/oct-19/participants", n: 1},
+	{list: "notes-from-a-neutron-star-exhibition/receive-transmission/receive-transmission-holders", n: 3},
+	{list: "moonbirds/nov-06/participants", n: 1},
+	{list: "grails/season-05/full-set-holders", n: 10},
+	// raffle.sh reuses $i from the giveaway-passes loop, which had exited
+	// with i=3.
+	{list: "grails/season-05-divergence-mint/projectIDs", n: 18, fold: 3},
+	{list: "grails/season-05-staff-mints/projectIDs", n: 18, fold: 1},
+	{list: "grails/season-05-staff-mints/projectIDs", n: 18, fold: 2},
+	{list: "grails/season-05-staff-mints/projectIDs", n: 18, fold: 3},
+	{list: "grails/season-05-staff-mints/projectIDs", n: 18, fold: 4},
+	{list: "talons/talons-squiggle/raffle-entries", n: 1},
+	{list: "talons/talons-squiggle-2/raffle-entries", n: 1},
+	{list: "talons/talons-apecoin/raffle-entries", distinct: 5},
+}
+
+// TestHistoricalDraws locks in the outputs of all raffles already conducted,
+// guaranteeing that the native implementation remains faithful to ethier.
+func TestHistoricalDraws(t *testing.T) {
+	for _, d := range historicalDraws {
+		name := d.list
+		if d.fold > 0 {
+			name = fmt.Sprintf("%s/fold-%d", name, d.fold)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			root := filepath.Join("..", filepath.FromSlash(d.list))
+
+			e, err := entropy.ParseFile(filepath.Join(filepath.Dir(root), entropy.FileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			seed := entropy.Fold(*e.Hash, d.fold).Hex()
+			buf, err := DecodeHex(seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(root)
+			if err != nil {
+				t.Fatalf("os.Open(%q) error %v", root, err)
+			}
+			defer f.Close()
+
+			var got [][]byte
+			if d.distinct > 0 {
+				got, err = DrawDistinct(buf, f, d.distinct)
+			} else {
+				got, err = Draw(buf, f, d.n)
+			}
+			if err != nil {
+				t.Fatalf("Draw[Distinct](%q, %q, …) error %v", seed, d.list, err)
+			}
+			gotOut := append(bytes.Join(got, []byte("\n")), '\n')
+
+			golden := filepath.Join("testdata", "golden", strings.ReplaceAll(name, "/", "_"))
+			if *update {
+				if err := os.WriteFile(golden, gotOut, 0644); err != nil {
+					t.Fatalf("os.WriteFile(%q) error %v", golden, err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile(%q) error %v", golden, err)
+			}
+			if diff := cmp.Diff(string(want), string(gotOut)); diff != "" {
+				t.Errorf("Draw(%q, %q, %d) diff against golden file %q (-want +got):\n%s", seed, d.list, d.n, golden, diff)
+			}
+		})
+	}
+}
+
+// TestWeightedEquivalence demonstrates that a list of repeated lines can be
+// replaced by an `address,weight` list without changing the outcome.
+func TestWeightedEquivalence(t *testing.T) {
+	const list = "../talons/talons-squiggle/raffle-entries"
+	buf, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", list, err)
+	}
+	e, err := entropy.ParseFile(filepath.Join(filepath.Dir(list), entropy.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	external, err := DecodeHex(e.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repeated, err := SortedNonEmpty(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("SortedNonEmpty(%q) error %v", list, err)
+	}
+
+	var (
+		order   []string
+		weights = make(map[string]int)
+	)
+	for _, l := range repeated {
+		if weights[string(l)] == 0 {
+			order = append(order, string(l))
+		}
+		weights[string(l)]++
+	}
+	var weighted bytes.Buffer
+	for _, addr := range order {
+		fmt.Fprintf(&weighted, "%s,%d\n", addr, weights[addr])
+	}
+	if got, want := len(order), len(repeated); got >= want {
+		t.Fatalf("test setup: %d distinct of %d lines; want fewer distinct", got, want)
+	}
+
+	expanded, err := SortedWeighted(&weighted)
+	if err != nil {
+		t.Fatalf("SortedWeighted([weighted %q]) error %v", list, err)
+	}
+	for _, lines := range [][][]byte{repeated, expanded} {
+		if err := Shuffle(external, lines); err != nil {
+			t.Fatalf("Shuffle() error %v", err)
+		}
+	}
+	if diff := cmp.Diff(repeated, expanded); diff != "" {
+		t.Errorf("Shuffle() of weighted list differs from repeated list; diff (-repeated +weighted):\n%s", diff)
+	}
+}
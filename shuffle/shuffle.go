@@ -0,0 +1,212 @@
+// Package shuffle reproduces, bit for bit, the `ethier shuffle` command
+// (github.com/divergencetech/ethier@v0.35.3) that has been used to conduct the
+// raffles in this repository. Having a native implementation means that draws
+// can be verified without installing a pinned, third-party binary.
+package shuffle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Draw reads all lines from r and returns the first n of them after shuffling
+// with the external entropy; if n is 0, or greater than the number of lines,
+// all are returned. See SortedNonEmpty() for the treatment of lines.
+func Draw(external []byte, r io.Reader, n int) ([][]byte, error) {
+	lines, err := SortedNonEmpty(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := Shuffle(external, lines); err != nil {
+		return nil, err
+	}
+
+	return Select(lines, n), nil
+}
+
+// DrawDistinct is equivalent to Draw() except that it returns the first n
+// distinct lines of the shuffle. Duplicate lines therefore act as additional
+// entries, increasing the chance of winning, but each value can only win once.
+// If n is 0 then all distinct lines are returned, in order of their first
+// appearance in the shuffle.
+//
+// This replaces the historical practice of piping the full shuffle through
+// `awk '!seen[$0]++' | head -n <n>`, with which it is equivalent.
+func DrawDistinct(external []byte, r io.Reader, n int) ([][]byte, error) {
+	lines, err := SortedNonEmpty(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := Shuffle(external, lines); err != nil {
+		return nil, err
+	}
+	return SelectDistinct(lines, n), nil
+}
+
+// Select returns the first n lines; if n is not positive, or greater than the
+// number of lines, all are returned, as for SelectFunc().
+func Select(lines [][]byte, n int) [][]byte {
+	if k := len(lines); n <= 0 || n > k {
+		n = k
+	}
+	return lines[:n]
+}
+
+// SelectDistinct returns the first n distinct lines; if n is 0, or greater than
+// the number of distinct lines, all distinct lines are returned.
+func SelectDistinct(lines [][]byte, n int) [][]byte {
+	seen := make(map[string]bool)
+	return SelectFunc(lines, n, func(l []byte) bool {
+		if seen[string(l)] {
+			return false
+		}
+		seen[string(l)] = true
+		return true
+	})
+}
+
+// SelectFunc returns the first n lines for which keep returns true; if n is 0,
+// or greater than the number of such lines, all of them are returned. Lines
+// are passed to keep in order, and only until n have been selected, so keep MAY
+// be stateful.
+func SelectFunc(lines [][]byte, n int, keep func([]byte) bool) [][]byte {
+	var out [][]byte
+	for _, l := range lines {
+		if n > 0 && len(out) == n {
+			break
+		}
+		if keep(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Shuffle shuffles lines in place. The lines are assumed to have already been
+// sorted (see SortedNonEmpty()) as they are themselves a source of entropy and
+// ordering would otherwise influence the outcome.
+func Shuffle(external []byte, lines [][]byte) error {
+	if len(external) == 0 {
+		return errors.New("no external entropy provided")
+	}
+
+	var e Entropy
+	e.HashAndFold(external)
+	e.HashAndFold(lines...)
+
+	e.Rand().Shuffle(len(lines), func(i, j int) {
+		lines[i], lines[j] = lines[j], lines[i]
+	})
+	return nil
+}
+
+// DecodeHex decodes hexadecimal entropy, as accepted by the --entropy flag of
+// `ethier shuffle`, with an optional 0x prefix.
+func DecodeHex(s string) ([]byte, error) {
+	buf, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("hex.DecodeString(%q): %v", s, err)
+	}
+	return buf, nil
+}
+
+// SortedNonEmpty reads all of r, splits the data by \n, trims surrounding
+// whitespace of each line, removes empty lines, and returns all remaining
+// values sorted with bytes.Compare().
+func SortedNonEmpty(r io.Reader) ([][]byte, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read input: %v", err)
+	}
+
+	var lines [][]byte
+	for _, l := range bytes.Split(buf, []byte("\n")) {
+		l = bytes.TrimSpace(l)
+		if len(l) == 0 {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return bytes.Compare(lines[i], lines[j]) == -1
+	})
+
+	return lines, nil
+}
+
+// SortedWeighted is equivalent to SortedNonEmpty() except that each line MAY
+// be of the form `value,weight`, in which case it is replaced by weight copies
+// of value; lines without a weight are equivalent to a weight of 1. Shuffling
+// the returned lines is therefore identical to shuffling a list in which each
+// value is repeated weight times, but without the need to commit such a list
+// to the repository.
+func SortedWeighted(r io.Reader) ([][]byte, error) {
+	lines, err := SortedNonEmpty(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var expanded [][]byte
+	for _, l := range lines {
+		idx := bytes.LastIndexByte(l, ',')
+		if idx == -1 {
+			expanded = append(expanded, l)
+			continue
+		}
+
+		val := bytes.TrimSpace(l[:idx])
+		if len(val) == 0 {
+			return nil, fmt.Errorf("empty value in weighted line %q", l)
+		}
+		w, err := strconv.ParseUint(string(bytes.TrimSpace(l[idx+1:])), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight in line %q: %v", l, err)
+		}
+		for i := uint64(0); i < w; i++ {
+			expanded = append(expanded, val)
+		}
+	}
+	sort.Slice(expanded, func(i, j int) bool {
+		return bytes.Compare(expanded[i], expanded[j]) == -1
+	})
+
+	return expanded, nil
+}
+
+// Entropy collects sources of entropy by xor-folding 8-byte words into the
+// existing value; it can then be used to create a seeded rand.Rand.
+type Entropy uint64
+
+// HashAndFold hashes the concatenation of data with Keccak256 and folds it into
+// the existing entropy value.
+func (e *Entropy) HashAndFold(data ...[]byte) {
+	e.Fold(crypto.Keccak256(data...))
+}
+
+// Fold pads buf to a multiple of 8 bytes, treats each 8-byte word as a
+// big-endian uint64, and sets e to the xor of all words and of the existing
+// entropy.
+//
+// Note that, to remain faithful to ethier, a buffer that is already a multiple
+// of 8 bytes is padded with an extra (no-op) zero word.
+func (e *Entropy) Fold(buf []byte) {
+	buf = append(buf, make([]byte, 8-len(buf)%8)...)
+	for i, n := 0, len(buf); i < n; i += 8 {
+		*e ^= Entropy(binary.BigEndian.Uint64(buf[i : i+8]))
+	}
+}
+
+// Rand returns a new rand.Rand with e as its Source seed.
+func (e Entropy) Rand() *rand.Rand {
+	return rand.New(rand.NewSource(int64(e)))
+}
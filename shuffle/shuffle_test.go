@@ -0,0 +1,286 @@
+package shuffle
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func asBytes(strs ...string) [][]byte {
+	var b [][]byte
+	for _, s := range strs {
+		b = append(b, []byte(s))
+	}
+	return b
+}
+
+func TestSortedNonEmpty(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [][]byte
+	}{
+		{
+			in: `foo
+			bar
+			baz`,
+			want: asBytes("bar", "baz", "foo"),
+		},
+		{
+			in: `
+
+			foo
+			bar
+			baz
+
+			`,
+			want: asBytes("bar", "baz", "foo"),
+		},
+		{
+			in: `
+
+baz
+
+			bar
+			qux
+
+			foo		`,
+			want: asBytes("bar", "baz", "foo", "qux"),
+		},
+		{
+			in:   "b\r\na\r\na\r\n",
+			want: asBytes("a", "a", "b"),
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := SortedNonEmpty(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("SortedNonEmpty(%q) error %v", tt.in, err)
+			continue
+		}
+
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("SortedNonEmpty(%q) diff (-want +got):\n%s", tt.in, diff)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		start Entropy
+		fold  [][]byte
+		want  Entropy
+	}{
+		{
+			name:  "big-endian padding 7 bytes",
+			start: 0,
+			fold:  [][]byte{{1}},
+			want:  1 << (7 * 8),
+		},
+		{
+			name:  "big-endian padding 5 bytes",
+			start: 0,
+			fold:  [][]byte{{1, 0, 1}},
+			want:  1<<(7*8) + 1<<(5*8),
+		},
+		{
+			name:  "no padding",
+			start: 0,
+			fold:  [][]byte{{1, 0, 1, 0, 0, 0, 0, 0}},
+			want:  1<<(7*8) + 1<<(5*8),
+		},
+		{
+			name:  "xor words",
+			start: 0,
+			fold: [][]byte{
+				{1, 0, 3, 0, 5, 0, 1, 1},
+				{1, 1, 1, 0, 7, 1, 0, 1},
+			},
+			want: 1<<(6*8) +
+				2<<(5*8) +
+				2<<(3*8) +
+				1<<(2*8) +
+				1<<(1*8),
+		},
+		{
+			name:  "xor with prior",
+			start: 42,
+			fold:  [][]byte{{1, 0, 0, 0, 0, 0, 0, 43}},
+			want:  1<<(7*8) + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.start
+			for _, f := range tt.fold {
+				got.Fold(f)
+			}
+			if got != tt.want {
+				t.Errorf("%T(%d) after folding %v; got %d; want %d", got, tt.start, tt.fold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntropyAsSeed(t *testing.T) {
+	truth := rand.New(rand.NewSource(42))
+	e := Entropy(42)
+
+	r := e.Rand()
+	for i := 0; i < 10; i++ {
+		got := r.Int63()
+		want := truth.Int63()
+		if got != want {
+			t.Errorf("Call %d to %T.Int63() got %d; want %d", i, r, got, want)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	const in = "c\nb\na\nd\ne\n"
+	entropy := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	all, err := Draw(entropy, strings.NewReader(in), 0)
+	if err != nil {
+		t.Fatalf("Draw(%#x, %q, 0) error %v", entropy, in, err)
+	}
+	if got, want := len(all), 5; got != want {
+		t.Fatalf("Draw(%#x, %q, 0) returned %d lines; want %d", entropy, in, got, want)
+	}
+
+	// Input order MUST NOT influence the outcome as lines are sorted first.
+	reordered, err := Draw(entropy, strings.NewReader("e\nd\nc\nb\na"), 0)
+	if err != nil {
+		t.Fatalf("Draw(%#x, [reordered], 0) error %v", entropy, err)
+	}
+	if diff := cmp.Diff(all, reordered); diff != "" {
+		t.Errorf("Draw() depends on input order; diff (-original +reordered):\n%s", diff)
+	}
+
+	for _, n := range []int{1, 3, 5, 6} {
+		got, err := Draw(entropy, strings.NewReader(in), n)
+		if err != nil {
+			t.Errorf("Draw(%#x, %q, %d) error %v", entropy, in, n, err)
+			continue
+		}
+		want := all
+		if n < len(all) {
+			want = all[:n]
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Draw(%#x, %q, %d) is not a prefix of full shuffle; diff (-want +got):\n%s", entropy, in, n, diff)
+		}
+	}
+
+	if _, err := Draw(nil, strings.NewReader(in), 0); err == nil {
+		t.Errorf("Draw(nil entropy, …) got nil error; want error")
+	}
+}
+
+func TestSelectDistinct(t *testing.T) {
+	lines := asBytes("a", "b", "a", "c", "b", "d")
+
+	tests := []struct {
+		n    int
+		want [][]byte
+	}{
+		{n: 0, want: asBytes("a", "b", "c", "d")},
+		{n: 1, want: asBytes("a")},
+		{n: 3, want: asBytes("a", "b", "c")},
+		{n: 10, want: asBytes("a", "b", "c", "d")},
+		{n: -1, want: asBytes("a", "b", "c", "d")},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, SelectDistinct(lines, tt.n)); diff != "" {
+			t.Errorf("SelectDistinct(%q, %d) diff (-want +got):\n%s", lines, tt.n, diff)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	lines := asBytes("a", "b", "c")
+
+	tests := []struct {
+		n    int
+		want [][]byte
+	}{
+		{n: 0, want: asBytes("a", "b", "c")},
+		{n: 2, want: asBytes("a", "b")},
+		{n: 10, want: asBytes("a", "b", "c")},
+		{n: -1, want: asBytes("a", "b", "c")},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, Select(lines, tt.n)); diff != "" {
+			t.Errorf("Select(%q, %d) diff (-want +got):\n%s", lines, tt.n, diff)
+		}
+	}
+}
+
+func TestSelectFunc(t *testing.T) {
+	lines := asBytes("a", "b", "c", "d", "e")
+
+	var calls int
+	notC := func(l []byte) bool {
+		calls++
+		return string(l) != "c"
+	}
+
+	got := SelectFunc(lines, 3, notC)
+	if diff := cmp.Diff(asBytes("a", "b", "d"), got); diff != "" {
+		t.Errorf("SelectFunc(%q, 3, …) diff (-want +got):\n%s", lines, diff)
+	}
+	if calls != 4 {
+		t.Errorf("SelectFunc(%q, 3, …) called keep %d times; want 4", lines, calls)
+	}
+
+	got = SelectFunc(lines, 0, notC)
+	if diff := cmp.Diff(asBytes("a", "b", "d", "e"), got); diff != "" {
+		t.Errorf("SelectFunc(%q, 0, …) diff (-want +got):\n%s", lines, diff)
+	}
+}
+
+func TestSortedWeighted(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    [][]byte
+		wantErr bool
+	}{
+		{
+			in:   "b,2\na,3\nc\n",
+			want: asBytes("a", "a", "a", "b", "b", "c"),
+		},
+		{
+			in:   "  b , 1 \n\na,0\nb\n",
+			want: asBytes("b", "b"),
+		},
+		{
+			in:      "a,x\n",
+			wantErr: true,
+		},
+		{
+			in:      "a,-1\n",
+			wantErr: true,
+		},
+		{
+			in:      ",1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := SortedWeighted(strings.NewReader(tt.in))
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("SortedWeighted(%q) got err %v; want err = %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("SortedWeighted(%q) diff (-want +got):\n%s", tt.in, diff)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package shuffle
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TraceVersion identifies the format of a Trace. Any change to the meaning of
+// existing fields MUST be accompanied by a new version.
+const TraceVersion = "proofxyz/raffles/shuffle.Trace/v1"
+
+// A Trace records every step of Shuffle(), allowing an independent
+// implementation, in any language, to be compared against it step by step.
+//
+// The seed is computed as
+//
+//	Seed = fold(keccak256(External)) ^ fold(keccak256(Lines[0] || Lines[1] || …))
+//
+// where fold() is described by Entropy.Fold(), and ExternalFold and LinesFold
+// are its two operands. A math/rand source seeded with int64(Seed) then drives
+// a Fisher–Yates shuffle, for i from len(Lines)-1 down to 1, choosing j in
+// [0, i] as follows:
+//
+//	do {
+//	    v = Source.Int63() >> 31          // a uint32
+//	    prod = v * (i+1)                  // as a uint64
+//	} while uint32(prod) < (2^32 - (i+1)) % (i+1)
+//	j = prod >> 32
+//
+// and swapping elements i and j. The rejection condition is only evaluated,
+// and therefore additional values only consumed, when uint32(prod) < i+1. This
+// is Daniel Lemire's unbiased multiply-shift method, as used by
+// math/rand.Rand.Shuffle().
+type Trace struct {
+	Version string `json:"version"`
+
+	External          hexutil.Bytes `json:"external"`
+	ExternalKeccak256 common.Hash   `json:"externalKeccak256"`
+	ExternalFold      string        `json:"externalFold"`
+	// Lines are the sorted lines, as passed to Shuffle(), before shuffling.
+	Lines          []string    `json:"lines"`
+	LinesKeccak256 common.Hash `json:"linesKeccak256"`
+	LinesFold      string      `json:"linesFold"`
+	// Seed is the hex encoding of the uint64 Entropy, and SeedInt64 the
+	// decimal encoding of the same bits as a (possibly negative) int64.
+	Seed      string `json:"seed"`
+	SeedInt64 string `json:"seedInt64"`
+
+	Swaps []Swap `json:"swaps"`
+	// Permutation[k] is the index, in Lines, of the k-th line after shuffling.
+	Permutation []int `json:"permutation"`
+}
+
+// A Swap is a single step of the Fisher–Yates shuffle, recorded in a Trace.
+type Swap struct {
+	I int `json:"i"`
+	// Int63 are all values consumed from the source to choose J, as decimal
+	// strings to avoid loss of precision in JavaScript, and Uint32 the values
+	// derived from each of them. More than one value implies that rejection
+	// sampling occurred.
+	Int63  []string `json:"int63"`
+	Uint32 []uint32 `json:"uint32"`
+	J      int      `json:"j"`
+}
+
+// ShuffleTrace is equivalent to Shuffle() but also returns a Trace of all
+// steps. The shuffle is reimplemented, explicitly, instead of calling
+// rand.Rand.Shuffle(); see the Trace documentation for the algorithm.
+func ShuffleTrace(external []byte, lines [][]byte) (*Trace, error) {
+	if len(external) == 0 {
+		return nil, errors.New("no external entropy provided")
+	}
+	if n := len(lines); n > 1<<31-1 {
+		return nil, fmt.Errorf("%d lines; trace only supports up to 2^31-1", n)
+	}
+
+	t := &Trace{
+		Version:           TraceVersion,
+		External:          external,
+		ExternalKeccak256: crypto.Keccak256Hash(external),
+		LinesKeccak256:    crypto.Keccak256Hash(lines...),
+		Lines:             make([]string, len(lines)),
+		Permutation:       make([]int, len(lines)),
+		Swaps:             []Swap{},
+	}
+
+	var ext, ls, e Entropy
+	ext.Fold(t.ExternalKeccak256.Bytes())
+	ls.Fold(t.LinesKeccak256.Bytes())
+	e.HashAndFold(external)
+	e.HashAndFold(lines...)
+	if e != ext^ls {
+		// Implies a bug in the documentation of the Trace.
+		return nil, fmt.Errorf("traced entropy %#x != %#x computed by %T.HashAndFold()", uint64(ext^ls), uint64(e), e)
+	}
+	t.ExternalFold = fmt.Sprintf("%#016x", uint64(ext))
+	t.LinesFold = fmt.Sprintf("%#016x", uint64(ls))
+	t.Seed = fmt.Sprintf("%#016x", uint64(e))
+	t.SeedInt64 = strconv.FormatInt(int64(e), 10)
+
+	for i, l := range lines {
+		t.Lines[i] = string(l)
+		t.Permutation[i] = i
+	}
+
+	src := rand.NewSource(int64(e))
+	for i := len(lines) - 1; i > 0; i-- {
+		s := Swap{I: i}
+		next := func() uint32 {
+			v := src.Int63()
+			u := uint32(v >> 31)
+			s.Int63 = append(s.Int63, strconv.FormatInt(v, 10))
+			s.Uint32 = append(s.Uint32, u)
+			return u
+		}
+
+		n := uint32(i + 1)
+		prod := uint64(next()) * uint64(n)
+		if low := uint32(prod); low < n {
+			thresh := -n % n
+			for low < thresh {
+				prod = uint64(next()) * uint64(n)
+				low = uint32(prod)
+			}
+		}
+		s.J = int(prod >> 32)
+
+		lines[i], lines[s.J] = lines[s.J], lines[i]
+		t.Permutation[i], t.Permutation[s.J] = t.Permutation[s.J], t.Permutation[i]
+		t.Swaps = append(t.Swaps, s)
+	}
+	return t, nil
+}
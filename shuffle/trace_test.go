@@ -0,0 +1,153 @@
+package shuffle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestShuffleTraceMatchesShuffle(t *testing.T) {
+	for n := 0; n <= 300; n += 13 {
+		for _, ext := range []string{"0x01", "0xdeadbeef", "0x88ec393de1ce2661fa3a5034adde28f02a18529df47ea4285ad0d025265baa0e"} {
+			var want, got [][]byte
+			for i := 0; i < n; i++ {
+				l := []byte(fmt.Sprintf("%04d", i))
+				want = append(want, l)
+				got = append(got, l)
+			}
+			external, err := DecodeHex(ext)
+			if err != nil {
+				t.Fatalf("DecodeHex(%q) error %v", ext, err)
+			}
+
+			if err := Shuffle(external, want); err != nil {
+				t.Fatalf("Shuffle(%s, [%d lines]) error %v", ext, n, err)
+			}
+			tr, err := ShuffleTrace(external, got)
+			if err != nil {
+				t.Fatalf("ShuffleTrace(%s, [%d lines]) error %v", ext, n, err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ShuffleTrace(%s, [%d lines]) shuffled differently to Shuffle(); diff (-want +got):\n%s", ext, n, diff)
+			}
+
+			buf, err := json.Marshal(tr)
+			if err != nil {
+				t.Fatalf("json.Marshal(%T) error %v", tr, err)
+			}
+			if err := replayTrace(buf, got); err != nil {
+				t.Errorf("ShuffleTrace(%s, [%d lines]) replay: %v", ext, n, err)
+			}
+		}
+	}
+}
+
+// replayTrace independently replays the JSON trace, as a third-party
+// implementation might, confirming that each step follows from the documented
+// algorithm and that the permutation results in the shuffled lines.
+func replayTrace(buf []byte, shuffled [][]byte) error {
+	var tr struct {
+		Version     string
+		Lines       []string
+		Permutation []int
+		Swaps       []struct {
+			I      int
+			Int63  []string
+			Uint32 []uint32
+			J      int
+		}
+	}
+	if err := json.Unmarshal(buf, &tr); err != nil {
+		return err
+	}
+	if tr.Version != TraceVersion {
+		return fmt.Errorf("version %q; want %q", tr.Version, TraceVersion)
+	}
+
+	perm := make([]int, len(tr.Lines))
+	for i := range perm {
+		perm[i] = i
+	}
+	wantI := len(tr.Lines) - 1
+	for _, s := range tr.Swaps {
+		if s.I != wantI {
+			return fmt.Errorf("swap i = %d; want %d", s.I, wantI)
+		}
+		wantI--
+
+		n := uint64(s.I + 1)
+		for k, str := range s.Int63 {
+			v, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return err
+			}
+			if uint32(v>>31) != s.Uint32[k] {
+				return fmt.Errorf("swap %d value %d: uint32 %d != %d >> 31", s.I, k, s.Uint32[k], v)
+			}
+			prod := uint64(s.Uint32[k]) * n
+			rejected := uint64(uint32(prod)) < (1<<32-n)%n
+			if last := k == len(s.Int63)-1; rejected == last {
+				return fmt.Errorf("swap %d value %d: rejected = %t; last value = %t", s.I, k, rejected, last)
+			}
+			if k == len(s.Int63)-1 && int(prod>>32) != s.J {
+				return fmt.Errorf("swap %d: j = %d; want %d", s.I, s.J, prod>>32)
+			}
+		}
+		perm[s.I], perm[s.J] = perm[s.J], perm[s.I]
+	}
+	if wantI > 0 {
+		return fmt.Errorf("missing swaps below i = %d", wantI+1)
+	}
+
+	if diff := cmp.Diff(perm, tr.Permutation); diff != "" {
+		return fmt.Errorf("permutation diff (-replayed +traced):\n%s", diff)
+	}
+	for k, idx := range perm {
+		if !bytes.Equal([]byte(tr.Lines[idx]), shuffled[k]) {
+			return fmt.Errorf("line %d of shuffle = %q; permuted line %q", k, shuffled[k], tr.Lines[idx])
+		}
+	}
+	return nil
+}
+
+func TestTraceGolden(t *testing.T) {
+	lines, err := SortedNonEmpty(bytes.NewReader([]byte("e\nd\nc\nb\na\n")))
+	if err != nil {
+		t.Fatalf("SortedNonEmpty() error %v", err)
+	}
+	tr, err := ShuffleTrace([]byte{1}, lines)
+	if err != nil {
+		t.Fatalf("ShuffleTrace() error %v", err)
+	}
+	got, err := json.MarshalIndent(tr, "", "  ")
+	if err != nil {
+		t.Fatalf("json.MarshalIndent(%T) error %v", tr, err)
+	}
+
+	path := filepath.Join("testdata", "trace-v1.json")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("os.WriteFile(%q) error %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", path, err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("ShuffleTrace() JSON diff (-%s +got):\n%s", path, diff)
+	}
+}
+
+func TestShuffleTraceErrors(t *testing.T) {
+	if _, err := ShuffleTrace(nil, [][]byte{[]byte("a")}); err == nil {
+		t.Error("ShuffleTrace(nil entropy) got nil error")
+	}
+}